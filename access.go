@@ -0,0 +1,320 @@
+package privileges
+
+import "fmt"
+
+// Op is a set of the rwx permission bits (4, 2, 1) requested from an object.
+// Effective also reports the object's special bits in the next octal digit.
+type Op uint8
+
+const (
+	OpExec Op = 1 << iota
+	OpWrite
+	OpRead
+	OpSticky
+	OpSetgid
+	OpSetuid
+)
+
+// String returns the bits in ls style (r-x). Special bits replace the x as ls
+// shows them: s or S for setuid and setgid, t or T for sticky.
+func (o Op) String() string {
+	sym := []byte(rwx(uint8(o)))
+
+	var c byte
+	switch {
+	case o&(OpSetuid|OpSetgid) != 0:
+		c = 's'
+	case o&OpSticky != 0:
+		c = 't'
+	default:
+		return string(sym)
+	}
+
+	if o&OpExec == 0 {
+		c -= 'a' - 'A'
+	}
+	sym[2] = c
+	return string(sym)
+}
+
+// Class identifies the part of an object's permissions that applied to a
+// user in an access decision.
+type Class uint8
+
+const (
+	ClassOwner      Class = iota // the owner's bits
+	ClassNamedUser               // an ACL entry naming the user
+	ClassGroup                   // the owning group's bits or entry
+	ClassNamedGroup              // an ACL entry naming one of the user's groups
+	ClassOther                   // the other bits
+)
+
+func (c Class) String() string {
+	return [...]string{"owner", "named user", "owning group", "named group", "other"}[c]
+}
+
+// Decision explains the outcome of an access check.
+type Decision struct {
+	User     string     // user the check was made for
+	Op       Op         // bits that were tested
+	Class    Class      // class of the object's permissions that matched
+	Group    string     // group through which a group class matched
+	Entry    string     // ACL entry that matched, if any
+	Mask     Op         // ACL mask limiting the matched entry, 7 if none
+	Perms    Op         // bits the matched class grants, after the mask
+	Override Capability // capability that overrode a denial, if any
+	Attr     Attr       // attribute that refused the access, if any
+	MAC      bool       // whether mandatory access control refused it
+	Allowed  bool       // final result
+}
+
+// String renders the decision as a single line for logs and admin tools.
+func (d *Decision) String() string {
+	var match string
+	switch {
+	case d.Entry != "":
+		match = fmt.Sprintf("%v entry %s", d.Class, d.Entry)
+	case d.Group != "":
+		match = fmt.Sprintf("%v %q", d.Class, d.Group)
+	default:
+		match = d.Class.String()
+	}
+
+	if d.Mask != 7 {
+		match += fmt.Sprintf(" masked by %v", d.Mask)
+	}
+
+	result := "denied"
+	if d.MAC {
+		result = "denied by mandatory access control"
+	} else if d.Attr != 0 {
+		result = "denied by attributes " + d.Attr.String()
+	} else if d.Override != "" {
+		result = "allowed by " + string(d.Override)
+	} else if d.Allowed {
+		result = "allowed"
+	}
+
+	return fmt.Sprintf("user %q requested %v: matched %s granting %v; %s", d.User, d.Op, match, d.Perms, result)
+}
+
+// Explain runs the access check for the bits in op on p and reports how it was
+// decided. CanRead, CanWrite and CanExec return its Allowed field.
+func (s *Session) Explain(p Privileged, op Op) *Decision {
+	return s.decide(p, op, false)
+}
+
+// Effective returns every bit the session is granted on p, as Explain would
+// decide them one at a time, in a single pass over its groups: the rwx bits
+// after ACLs, capability overrides, attributes and mandatory access control.
+// The setuid and setgid bits of p are included when exec is granted, and the
+// sticky bit always. Write is left out of append-only objects; use CanAppend.
+func (s *Session) Effective(p Privileged) Op {
+	r := p.Rules()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	perms := s.grants(r)
+
+	for _, op := range []Op{OpRead, OpWrite, OpExec} {
+		if perms&op == 0 && s.override(p, op) != "" {
+			perms |= op
+		}
+		if perms&op != 0 && !s.mac(r, op) {
+			perms &^= op
+		}
+	}
+
+	if r.attrs != 0 {
+		perms &^= OpWrite
+	}
+
+	special := Op(r.rules>>12&7) << 3
+	if perms&OpExec != 0 {
+		perms |= special & (OpSetuid | OpSetgid)
+	}
+	perms |= special & OpSticky
+
+	return perms
+}
+
+// grants returns the rwx bits the permission bits and ACL of r grant the
+// session. It follows dac, except that a session in several matching groups is
+// granted the union of their entries, as dac would allow each bit alone.
+func (s *Session) grants(r *Rules) Op {
+	if s.User == r.owner {
+		return Op(r.rules >> 8 & 7)
+	}
+
+	mask, group := Op(7), Op(r.rules>>4&7)
+	if r.acl != nil {
+		mask, group = group, Op(r.acl.group)
+		for _, e := range r.acl.entries {
+			if e.Tag == ACLUser && e.Qualifier == s.User {
+				return Op(e.Perms) & mask
+			}
+		}
+	}
+
+	var perms Op
+	matched := false
+	if s.member(r.group) {
+		matched = true
+		perms = group
+	}
+
+	if r.acl != nil {
+		for _, e := range r.acl.entries {
+			if e.Tag == ACLGroup && s.member(e.Qualifier) {
+				matched = true
+				perms |= Op(e.Perms)
+			}
+		}
+	}
+
+	if matched {
+		return perms & mask
+	}
+	return Op(r.rules & 7)
+}
+
+// decide runs the access check. Writes to immutable objects are refused, and
+// so are writes to append-only objects unless appending is true. Mandatory
+// access control applies last, to accesses allowed by everything else.
+func (s *Session) decide(p Privileged, op Op, appending bool) *Decision {
+	r := p.Rules()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d := s.dac(r, op)
+	if !d.Allowed {
+		d.Override = s.override(p, op)
+		d.Allowed = d.Override != ""
+	}
+
+	if op&OpWrite != 0 {
+		attrs := r.attrs & AttrImmutable
+		if !appending {
+			attrs |= r.attrs & AttrAppend
+		}
+		if attrs != 0 {
+			d.Attr = attrs
+			d.Allowed = false
+		}
+	}
+
+	if d.Allowed && !s.mac(r, op) {
+		d.MAC = true
+		d.Allowed = false
+	}
+
+	return d
+}
+
+// err returns the error for a denied decision, telling mandatory access
+// control denials apart from the rest.
+func (d *Decision) err() error {
+	if d.MAC {
+		return errDeniedMAC
+	}
+	return errDenied
+}
+
+// override returns the capability that lets the session bypass the permission
+// bits of p, as the kernel allows, or "" if there is none: CapDACReadSearch
+// for reading anything and searching any directory, and CapDACOverride for
+// reading and writing anything, searching any directory and executing files
+// that have at least one execute bit set.
+func (s *Session) override(p Privileged, want Op) Capability {
+	if !s.p.dacOverride {
+		return ""
+	}
+
+	if s.Capable(CapDACReadSearch) && (want == OpRead || isDir(p) && want&OpWrite == 0) {
+		return CapDACReadSearch
+	}
+
+	if !s.Capable(CapDACOverride) {
+		return ""
+	}
+
+	if want&OpExec == 0 || isDir(p) || p.Rules().rules&0x0111 != 0 {
+		return CapDACOverride
+	}
+
+	return ""
+}
+
+// dac runs the POSIX.1e access check algorithm for the requested rwx bits:
+// the owner entry applies to the owner, then named user entries, then every
+// group entry the session is a member of, and finally the other entry. Named
+// user and group entries, and the owning group of rules with an extended ACL,
+// are limited by the mask.
+func (s *Session) dac(r *Rules, want Op) *Decision {
+	d := &Decision{User: s.User, Op: want, Mask: 7}
+
+	if s.User == r.owner {
+		d.Class = ClassOwner
+		d.Perms = Op(r.rules >> 8 & 7)
+		d.Allowed = d.Perms&want == want
+		return d
+	}
+
+	group := Op(r.rules >> 4 & 7)
+	if r.acl != nil {
+		d.Mask = group
+		group = Op(r.acl.group)
+		for _, e := range r.acl.entries {
+			if e.Tag == ACLUser && e.Qualifier == s.User {
+				d.Class = ClassNamedUser
+				d.Entry = e.String()
+				d.Perms = Op(e.Perms) & d.Mask
+				d.Allowed = d.Perms&want == want
+				return d
+			}
+		}
+	}
+
+	matched := false
+	if s.member(r.group) {
+		matched = true
+		d.Class = ClassGroup
+		d.Group = r.group
+		d.Perms = group & d.Mask
+		if d.Perms&want == want {
+			d.Allowed = true
+			return d
+		}
+	}
+
+	if r.acl != nil {
+		for _, e := range r.acl.entries {
+			if e.Tag != ACLGroup || !s.member(e.Qualifier) {
+				continue
+			}
+
+			perms := Op(e.Perms) & d.Mask
+			if !matched || perms&want == want {
+				d.Class = ClassNamedGroup
+				d.Group = e.Qualifier
+				d.Entry = e.String()
+				d.Perms = perms
+			}
+			matched = true
+
+			if perms&want == want {
+				d.Allowed = true
+				return d
+			}
+		}
+	}
+
+	if matched {
+		return d
+	}
+
+	d.Class = ClassOther
+	d.Perms = Op(r.rules & 7)
+	d.Allowed = d.Perms&want == want
+	return d
+}
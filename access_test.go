@@ -0,0 +1,105 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestAccess00(t *testing.T) {
+	p.newUser("Lana", "Kane")
+	p.newGroup("field")
+	p.newGroup("ops")
+	p.addToGroup("Lana", "field")
+	p.addToGroup("Lana", "ops")
+	defer p.deleteGroup("ops")
+	defer p.deleteGroup("field")
+	defer p.deleteGroup("Lana")
+	defer p.deleteUser("Lana")
+
+	s, err := p.Login("Lana", "Kane")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	r, _ := NewRules(root, "field", "0640")
+	d := s.Explain(r, OpWrite)
+	if d.Allowed || d.Class != ClassGroup || d.Group != "field" || d.Perms != OpRead {
+		t.Error(d)
+	}
+
+	want := `user "Lana" requested -w-: matched owning group "field" granting r--; denied`
+	if d.String() != want {
+		t.Error(d)
+	}
+
+	a, _ := ParseACL("user::rw-,group::r--,group:ops:rw-,mask::r--,other::---")
+	r.SetACL(a)
+	d = s.Explain(r, OpRead|OpWrite)
+	if d.Allowed || d.Class != ClassGroup || d.Mask != OpRead {
+		t.Error(d)
+	}
+
+	a, _ = ParseACL("user::rw-,group::r--,group:ops:rw-,mask::rw-,other::---")
+	r.SetACL(a)
+	d = s.Explain(r, OpRead|OpWrite)
+	if !d.Allowed || d.Class != ClassNamedGroup || d.Group != "ops" || d.Entry != "group:ops:rw-" {
+		t.Error(d)
+	}
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	r, _ = NewRules("Lana", "Lana", "0000")
+	d = su.Explain(r, OpWrite)
+	want = `user "root" requested -w-: matched other granting ---; allowed by CAP_DAC_OVERRIDE`
+	if !d.Allowed || d.Class != ClassOther || d.Override != CapDACOverride || d.String() != want {
+		t.Error(d)
+	}
+}
+
+func TestAccess01(t *testing.T) {
+	p.newUser("Cyril", "Figgis")
+	p.newGroup("accounts")
+	p.newGroup("audit")
+	p.addToGroup("Cyril", "accounts")
+	p.addToGroup("Cyril", "audit")
+	defer p.deleteGroup("audit")
+	defer p.deleteGroup("accounts")
+	defer p.deleteGroup("Cyril")
+	defer p.deleteUser("Cyril")
+
+	s, err := p.Login("Cyril", "Figgis")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	r, _ := NewRules(root, "accounts", "2750")
+	if e := s.Effective(r); e != OpRead|OpExec|OpSetgid || e.String() != "r-s" {
+		t.Error(e)
+	}
+
+	a, _ := ParseACL("user::rwx,group::r--,group:audit:-w-,mask::rw-,other::---")
+	r.SetACL(a)
+	if e := s.Effective(r); e != OpRead|OpWrite || e.String() != "rw-" {
+		t.Error(e)
+	}
+
+	r, _ = NewRules(root, root, "1000")
+	if e := s.Effective(r); e != OpSticky || e.String() != "--T" {
+		t.Error(e)
+	}
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	r, _ = NewRules("Cyril", "Cyril", "0600")
+	if e := su.Effective(r); e != OpRead|OpWrite {
+		t.Error(e)
+	}
+
+	r.attrs = AttrAppend
+	if e := su.Effective(r); e != OpRead {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,192 @@
+package privileges
+
+import "time"
+
+// status returns the error refusing a login to the account in rec, if any.
+func (rec *record) status() error {
+	switch {
+	case rec.disabled:
+		return errAccountDisabled
+	case rec.locked:
+		return errAccountLocked
+	case rec.expires != 0 && time.Now().Unix() >= rec.expires:
+		return errAccountExpired
+	}
+	return nil
+}
+
+// setStatus sets one of the locked and disabled flags of a user.
+func (p *Privileges) setStatus(username, column string, on bool) error {
+
+	if username == root {
+		return errRoot
+	}
+
+	res, err := p.db.Exec("UPDATE users SET "+column+"=? WHERE name=?", on, username)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBadName
+	}
+
+	if on {
+		p.endSessions(username)
+	}
+	return nil
+
+}
+
+func (p *Privileges) setExpiry(username string, t time.Time) error {
+
+	if username == root {
+		return errRoot
+	}
+
+	var expires int64
+	if !t.IsZero() {
+		expires = t.Unix()
+	}
+
+	res, err := p.db.Exec("UPDATE users SET expires=? WHERE name=?", expires, username)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBadName
+	}
+
+	for _, s := range p.sessions {
+		if s.User == username {
+			s.expires = expires
+		}
+	}
+	return nil
+
+}
+
+// endSessions logs out every live session of a user.
+func (p *Privileges) endSessions(username string) {
+
+	for sid, s := range p.sessions {
+		if s.User == username {
+			delete(p.sessions, sid)
+		}
+	}
+
+}
+
+// Expiry returns the time a user's account expires, or the zero Time if it
+// never does.
+func (s *Session) Expiry(username string) (time.Time, error) {
+	if !s.valid() {
+		return time.Time{}, errBadSession
+	}
+
+	var expires int64
+	err := s.p.db.QueryRow("SELECT expires FROM users WHERE name=?", username).Scan(&expires)
+	if err != nil {
+		return time.Time{}, errBadName
+	}
+
+	if expires == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(expires, 0), nil
+}
+
+// LockUser locks a user's account, refusing its logins and ending its live
+// sessions. It requires CapUserAdmin, root can't be locked, and only a
+// superuser can lock root group members.
+func (s *Session) LockUser(username string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
+	return s.p.setStatus(username, "locked", true)
+}
+
+// UnlockUser unlocks a user's account. It requires CapUserAdmin, and only a
+// superuser can unlock root group members.
+func (s *Session) UnlockUser(username string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
+	return s.p.setStatus(username, "locked", false)
+}
+
+// DisableUser disables a user's account, refusing its logins and ending its
+// live sessions. It requires CapUserAdmin, root can't be disabled, and only a
+// superuser can disable root group members.
+func (s *Session) DisableUser(username string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
+	return s.p.setStatus(username, "disabled", true)
+}
+
+// EnableUser enables a user's account. It requires CapUserAdmin, and only a
+// superuser can enable root group members.
+func (s *Session) EnableUser(username string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
+	return s.p.setStatus(username, "disabled", false)
+}
+
+// SetExpiry sets the time a user's account expires, or the zero Time for
+// never. Logins are refused from then on and live sessions stop being valid.
+// It requires CapUserAdmin, root can't be given an expiry, and only a
+// superuser can set one on root group members.
+func (s *Session) SetExpiry(username string, t time.Time) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
+	return s.p.setExpiry(username, t)
+}
@@ -0,0 +1,122 @@
+package privileges
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccount00(t *testing.T) {
+	p.newUser("Krieger", "Algernop")
+	defer p.deleteGroup("Krieger")
+	defer p.deleteUser("Krieger")
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	if su.LockUser(root) != errRoot || su.DisableUser("nobody") != errBadName {
+		t.Error(nil)
+	}
+
+	k, _ := p.Login("Krieger", "Algernop")
+	r, _ := NewRules("Krieger", "Krieger", "0600")
+	g := NewGuarded("lab", r)
+
+	if su.LockUser("Krieger") != nil {
+		t.Fatal(nil)
+	}
+
+	if _, err := g.Get(k); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := k.Umask("0777"); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := p.Login("Krieger", "Algernop"); err != errAccountLocked {
+		t.Error(err)
+	}
+
+	if _, err := p.Login("Krieger", "wrong"); err != errBadCredentials {
+		t.Error(err)
+	}
+
+	su.UnlockUser("Krieger")
+	su.DisableUser("Krieger")
+	if _, err := p.Login("Krieger", "Algernop"); err != errAccountDisabled {
+		t.Error(err)
+	}
+
+	su.EnableUser("Krieger")
+	k, err := p.Login("Krieger", "Algernop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Logout()
+
+	if _, err := k.Gid("guest", "Krieger"); err != errNotCapable {
+		t.Error(err)
+	}
+
+	if k.LockUser("guest") != errNotCapable {
+		t.Error(nil)
+	}
+
+	if su.SetExpiry("Krieger", time.Now().Add(-time.Hour)) != nil {
+		t.Error(nil)
+	}
+
+	if _, err := g.Get(k); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := p.LoginHash("Krieger", k.Hash); err != errAccountExpired {
+		t.Error(err)
+	}
+
+	su.SetExpiry("Krieger", time.Time{})
+	if e, _ := su.Expiry("Krieger"); !e.IsZero() {
+		t.Error(e)
+	}
+
+	if _, err := g.Get(k); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccount01(t *testing.T) {
+	p.newUser("Woodhouse", "Woodhouse")
+	p.grantUserCapability("Woodhouse", CapUserAdmin)
+	defer p.deleteGroup("Woodhouse")
+	defer p.deleteUser("Woodhouse")
+
+	p.newUser("Malory", "Malory")
+	p.addToGroup("Malory", root)
+	defer p.deleteGroup("Malory")
+	defer p.deleteUser("Malory")
+
+	w, _ := p.Login("Woodhouse", "Woodhouse")
+	defer w.Logout()
+
+	if w.LockUser("Malory") != errNotSU || w.DisableUser("Malory") != errNotSU {
+		t.Error(nil)
+	}
+
+	if w.SetExpiry("Malory", time.Now()) != errNotSU {
+		t.Error(nil)
+	}
+
+	if w.SetPasswordAging("Malory", &PasswordAging{MustChange: true}) != errNotSU {
+		t.Error(nil)
+	}
+
+	m, err := p.Login("Malory", "Malory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Logout()
+
+	if m.MustChangePassword() {
+		t.Error(nil)
+	}
+}
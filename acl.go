@@ -0,0 +1,405 @@
+package privileges
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ACLTag identifies the kind of an ACL entry.
+type ACLTag uint8
+
+// ACL entry tags, in the order getfacl prints them.
+const (
+	ACLUserObj ACLTag = iota
+	ACLUser
+	ACLGroupObj
+	ACLGroup
+	ACLMask
+	ACLOther
+)
+
+// ACLEntry is a single POSIX.1e access control list entry. Qualifier names the
+// user or group of ACLUser and ACLGroup entries and is empty otherwise. Perms
+// holds the rwx bits (4, 2, 1).
+type ACLEntry struct {
+	Tag       ACLTag
+	Qualifier string
+	Perms     uint8
+}
+
+// ACL is a POSIX.1e access control list.
+type ACL []ACLEntry
+
+// acl holds the extended part of a Rules' access control list. When present
+// the group bits of the rules act as the ACL mask.
+type acl struct {
+	group   uint8 // permissions of the owning group
+	entries ACL   // named user and named group entries
+}
+
+// ParseACL parses an access control list in the text form used by getfacl and
+// setfacl. Entries are separated by newlines or commas, comments starting with
+// # are ignored, and tags may be abbreviated (u:bob:rx, g::r, m::rx, o::-).
+// Default entries are refused; use ParseACLs for getfacl output of
+// directories.
+func ParseACL(text string) (ACL, error) {
+	a, def, err := ParseACLs(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if def != nil {
+		return nil, fmt.Errorf("%w: unexpected default entries", errBadACL)
+	}
+	return a, nil
+}
+
+// ParseACLs is like ParseACL but also accepts default entries, prefixed with
+// default: or d: as getfacl prints them, and returns them separately as the
+// default ACL. Either list is nil if the text has none of its entries.
+func ParseACLs(text string) (access, def ACL, err error) {
+	for _, line := range strings.FieldsFunc(text, func(c rune) bool { return c == '\n' || c == ',' }) {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		list := &access
+		if i := strings.IndexByte(line, ':'); i >= 0 && (line[:i] == "default" || line[:i] == "d") {
+			list = &def
+			line = line[i+1:]
+		}
+
+		e, err := parseACLEntry(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		*list = append(*list, e)
+	}
+
+	if access != nil || def == nil {
+		if err := access.valid(); err != nil {
+			return nil, nil, err
+		}
+		access.sort()
+	}
+
+	if def != nil {
+		if err := def.valid(); err != nil {
+			return nil, nil, fmt.Errorf("default %w", err)
+		}
+		def.sort()
+	}
+
+	return access, def, nil
+}
+
+func parseACLEntry(text string) (ACLEntry, error) {
+	var e ACLEntry
+
+	fields := strings.Split(text, ":")
+	if len(fields) != 3 {
+		return e, fmt.Errorf("%w %q: expected tag:qualifier:perms", errBadACL, text)
+	}
+
+	e.Qualifier = fields[1]
+	switch fields[0] {
+	case "user", "u":
+		e.Tag = ACLUserObj
+		if e.Qualifier != "" {
+			e.Tag = ACLUser
+		}
+	case "group", "g":
+		e.Tag = ACLGroupObj
+		if e.Qualifier != "" {
+			e.Tag = ACLGroup
+		}
+	case "mask", "m":
+		e.Tag = ACLMask
+	case "other", "o":
+		e.Tag = ACLOther
+	default:
+		return e, fmt.Errorf("%w %q: unknown tag %q", errBadACL, text, fields[0])
+	}
+
+	for _, c := range fields[2] {
+		switch c {
+		case 'r':
+			e.Perms |= 4
+		case 'w':
+			e.Perms |= 2
+		case 'x':
+			e.Perms |= 1
+		case '-':
+		default:
+			return e, fmt.Errorf("%w %q: unknown permission %q", errBadACL, text, c)
+		}
+	}
+
+	return e, nil
+}
+
+// String returns the entry in the form tag:qualifier:perms (user:bob:r-x).
+func (e ACLEntry) String() string {
+	tag := [...]string{"user", "user", "group", "group", "mask", "other"}[e.Tag]
+	return tag + ":" + e.Qualifier + ":" + rwx(e.Perms)
+}
+
+// rwx returns the rwx bits in ls style (r-x).
+func rwx(perms uint8) string {
+	sym := []byte("---")
+	if perms&4 != 0 {
+		sym[0] = 'r'
+	}
+	if perms&2 != 0 {
+		sym[1] = 'w'
+	}
+	if perms&1 != 0 {
+		sym[2] = 'x'
+	}
+	return string(sym)
+}
+
+// String returns the access control list in getfacl's text form, one entry per
+// line.
+func (a ACL) String() string {
+	var lines []string
+	for _, e := range a {
+		lines = append(lines, e.String()+"\n")
+	}
+	return strings.Join(lines, "")
+}
+
+// valid checks that the list is a well formed POSIX.1e access control list:
+// exactly one owner, owning group and other entry, no duplicate named entries,
+// and a mask whenever named entries are present.
+func (a ACL) valid() error {
+	var count [ACLOther + 1]int
+	named := make(map[ACLEntry]bool)
+
+	for _, e := range a {
+		if e.Tag > ACLOther {
+			return fmt.Errorf("%w: unknown tag %d", errBadACL, e.Tag)
+		}
+		count[e.Tag]++
+
+		switch e.Tag {
+		case ACLUser, ACLGroup:
+			if e.Qualifier == "" {
+				return fmt.Errorf("%w: %v has no qualifier", errBadACL, e)
+			}
+			key := ACLEntry{Tag: e.Tag, Qualifier: e.Qualifier}
+			if named[key] {
+				return fmt.Errorf("%w: duplicate entry for %v", errBadACL, e)
+			}
+			named[key] = true
+		default:
+			if e.Qualifier != "" {
+				return fmt.Errorf("%w: %v must not have a qualifier", errBadACL, e)
+			}
+		}
+	}
+
+	if count[ACLUserObj] != 1 || count[ACLGroupObj] != 1 || count[ACLOther] != 1 {
+		return fmt.Errorf("%w: need exactly one user::, group:: and other:: entry", errBadACL)
+	}
+
+	if count[ACLMask] > 1 {
+		return fmt.Errorf("%w: more than one mask entry", errBadACL)
+	}
+
+	if count[ACLMask] == 0 && (count[ACLUser] != 0 || count[ACLGroup] != 0) {
+		return fmt.Errorf("%w: named entries require a mask entry", errBadACL)
+	}
+
+	return nil
+}
+
+func (a ACL) sort() {
+	sort.SliceStable(a, func(i, j int) bool {
+		if a[i].Tag != a[j].Tag {
+			return a[i].Tag < a[j].Tag
+		}
+		return a[i].Qualifier < a[j].Qualifier
+	})
+}
+
+// ACL returns the access control list of the rules. Rules without extended
+// entries yield the minimal list equivalent to their permission bits.
+func (r *Rules) ACL() ACL {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.accessACL()
+}
+
+func (r *Rules) accessACL() ACL {
+	a := ACL{{Tag: ACLUserObj, Perms: uint8(r.rules >> 8 & 7)}}
+
+	if r.acl == nil {
+		a = append(a, ACLEntry{Tag: ACLGroupObj, Perms: uint8(r.rules >> 4 & 7)})
+	} else {
+		a = append(a, r.acl.entries...)
+		a = append(a, ACLEntry{Tag: ACLGroupObj, Perms: r.acl.group})
+		a = append(a, ACLEntry{Tag: ACLMask, Perms: uint8(r.rules >> 4 & 7)})
+	}
+
+	a = append(a, ACLEntry{Tag: ACLOther, Perms: uint8(r.rules & 7)})
+	a.sort()
+
+	return a
+}
+
+// SetACL replaces the access control list of the rules. The owner, group and
+// other entries update the permission bits; if the list has a mask entry it
+// is stored in the group bits, as chmod and ls treat it on Linux.
+func (r *Rules) SetACL(a ACL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.setACL(a)
+}
+
+func (r *Rules) setACL(a ACL) error {
+	if err := a.valid(); err != nil {
+		return err
+	}
+
+	var user, group, mask, other uint8
+	var ext *acl
+	for _, e := range a {
+		switch e.Tag {
+		case ACLUserObj:
+			user = e.Perms & 7
+		case ACLGroupObj:
+			group = e.Perms & 7
+		case ACLOther:
+			other = e.Perms & 7
+		case ACLMask:
+			mask = e.Perms & 7
+			if ext == nil {
+				ext = new(acl)
+			}
+		default:
+			if ext == nil {
+				ext = new(acl)
+			}
+			ext.entries = append(ext.entries, ACLEntry{e.Tag, e.Qualifier, e.Perms & 7})
+		}
+	}
+
+	if ext != nil {
+		ext.group = group
+		ext.entries.sort()
+		group = mask
+	}
+
+	r.rules = r.rules&^0x0777 | uint16(user)<<8 | uint16(group)<<4 | uint16(other)
+	r.acl = ext
+
+	return nil
+}
+
+// DefaultACL returns the default access control list that a directory passes
+// on to entries created inside it, or nil if it has none.
+func (r *Rules) DefaultACL() ACL {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.def == nil {
+		return nil
+	}
+	return append(ACL(nil), r.def...)
+}
+
+// SetDefaultACL replaces the default access control list of a directory. An
+// empty list removes it.
+func (r *Rules) SetDefaultACL(a ACL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.setDefaultACL(a)
+}
+
+func (r *Rules) setDefaultACL(a ACL) error {
+	if len(a) == 0 {
+		r.def = nil
+		return nil
+	}
+
+	if err := a.valid(); err != nil {
+		return err
+	}
+
+	r.def = append(ACL(nil), a...)
+	r.def.sort()
+
+	return nil
+}
+
+// ACLText returns the access control list and default access control list of
+// the rules in getfacl's text form, default entries prefixed with default:.
+func (r *Rules) ACLText() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	text := r.accessACL().String()
+	for _, e := range r.def {
+		text += "default:" + e.String() + "\n"
+	}
+	return text
+}
+
+// SetACLText replaces the access control list and default access control list
+// of the rules with those parsed from text by ParseACLs. Text without default
+// entries removes the default ACL, as setfacl --set does.
+func (r *Rules) SetACLText(text string) error {
+	a, def, err := ParseACLs(text)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a == nil {
+		a = r.accessACL()
+	}
+
+	if err = r.setACL(a); err != nil {
+		return err
+	}
+	return r.setDefaultACL(def)
+}
+
+// inherit gives the rules the access control list a new entry receives from
+// the default ACL def of its directory. As with open(2) and mkdir(2) the
+// requested permission bits limit the owner, mask (or owning group when there
+// is no mask) and other entries.
+func (r *Rules) inherit(def ACL) {
+	a := append(ACL(nil), def...)
+
+	masked := false
+	for _, e := range a {
+		if e.Tag == ACLMask {
+			masked = true
+		}
+	}
+
+	for i, e := range a {
+		switch {
+		case e.Tag == ACLUserObj:
+			a[i].Perms &= uint8(r.rules >> 8 & 7)
+		case e.Tag == ACLMask, e.Tag == ACLGroupObj && !masked:
+			a[i].Perms &= uint8(r.rules >> 4 & 7)
+		case e.Tag == ACLOther:
+			a[i].Perms &= uint8(r.rules & 7)
+		}
+	}
+
+	r.setACL(a)
+}
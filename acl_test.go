@@ -0,0 +1,145 @@
+package privileges
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestACL00(t *testing.T) {
+	bad := []string{
+		"",
+		"user::rwx",
+		"user::rwx\ngroup::r-x\nother::---\nother::---",
+		"user::rwx\nuser:bob:r-x\ngroup::r-x\nother::---",
+		"user::rwx\nuser:bob:r-x\nuser:bob:r--\ngroup::r-x\nmask::r-x\nother::---",
+		"user::rwx\ngroup::r-x\nmask:qa:r-x\nother::---",
+		"user::rwz\ngroup::r-x\nother::---",
+		"owner::rwx\ngroup::r-x\nother::---",
+		"user:rwx\ngroup::r-x\nother::---",
+	}
+
+	for _, text := range bad {
+		if _, err := ParseACL(text); !errors.Is(err, errBadACL) {
+			t.Error(text)
+		}
+	}
+}
+
+func TestACL01(t *testing.T) {
+	text := "# file: report\n# owner: alan\n# group: sisatech\n" +
+		"user::rw-\nuser:bob:rwx\t#effective:r-x\ngroup::r--\ngroup:qa:rw-\nmask::r-x\nother::---\n"
+
+	a, err := ParseACL(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := NewRules("alan", "sisatech", "0777")
+	if err = r.SetACL(a); err != nil {
+		t.Error(err)
+	}
+
+	if r.Octal() != "0650" {
+		t.Error(r.Octal())
+	}
+
+	want := "user::rw-\nuser:bob:rwx\ngroup::r--\ngroup:qa:rw-\nmask::r-x\nother::---\n"
+	if r.ACL().String() != want {
+		t.Error(r.ACL().String())
+	}
+
+	a, _ = ParseACL("u::rw,g::r,o::r")
+	r.SetACL(a)
+	if r.Octal() != "0644" || r.ACL().String() != "user::rw-\ngroup::r--\nother::r--\n" {
+		t.Error(nil)
+	}
+}
+
+func TestACL02(t *testing.T) {
+	p.newUser("Sterling", "Archer")
+	p.newUser("Malory", "Archer")
+	p.newGroup("ISIS")
+	p.addToGroup("Malory", "ISIS")
+	defer p.deleteGroup("ISIS")
+	defer p.deleteGroup("Sterling")
+	defer p.deleteUser("Sterling")
+	defer p.deleteGroup("Malory")
+	defer p.deleteUser("Malory")
+
+	sterling, err := p.Login("Sterling", "Archer")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer sterling.Logout()
+
+	malory, err := p.Login("Malory", "Archer")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer malory.Logout()
+
+	r, _ := NewRules(root, root, "0700")
+	a, _ := ParseACL("user::rwx,user:Sterling:rwx,group::---,group:ISIS:rw-,mask::r-x,other::---")
+	r.SetACL(a)
+
+	if !sterling.CanRead(r) || sterling.CanWrite(r) || !sterling.CanExec(r) {
+		t.Error(nil)
+	}
+
+	if !malory.CanRead(r) || malory.CanWrite(r) || malory.CanExec(r) {
+		t.Error(nil)
+	}
+
+	a, _ = ParseACL("user::rwx,group::rwx,mask::rwx,other::rwx,group:ISIS:---")
+	r.SetACL(a)
+
+	if malory.CanRead(r) || !sterling.CanRead(r) {
+		t.Error(nil)
+	}
+}
+
+func TestACL03(t *testing.T) {
+	text := `# file: shared
+# owner: root
+# group: root
+user::rwx
+user:bob:r-x	#effective:r-x
+group::r-x
+mask::r-x
+other::---
+default:user::rwx
+default:user:bob:rwx
+d:group::r-x
+default:mask::rwx
+default:other::---
+`
+
+	if _, err := ParseACL(text); !errors.Is(err, errBadACL) {
+		t.Error(err)
+	}
+
+	a, def, err := ParseACLs(text)
+	if err != nil || len(a) != 5 || len(def) != 5 || def[1].Qualifier != "bob" || def[1].Perms != 7 {
+		t.Fatal(a, def, err)
+	}
+
+	if _, _, err = ParseACLs("default:user::rwx"); err == nil {
+		t.Error(nil)
+	}
+
+	r, _ := NewRules(root, root, "0700")
+	if r.SetACLText(text) != nil || r.Octal() != "0750" || len(r.DefaultACL()) != 5 {
+		t.Error(r.Octal())
+	}
+
+	q, _ := NewRules(root, root, "0000")
+	if q.SetACLText(r.ACLText()) != nil || q.ACLText() != r.ACLText() {
+		t.Error(q.ACLText())
+	}
+
+	want := "user::rwx\nuser:bob:r-x\ngroup::r-x\nmask::r-x\nother::---\n" +
+		"default:user::rwx\ndefault:user:bob:rwx\ndefault:group::r-x\ndefault:mask::rwx\ndefault:other::---\n"
+	if r.ACLText() != want {
+		t.Error(r.ACLText())
+	}
+}
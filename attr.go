@@ -0,0 +1,83 @@
+package privileges
+
+// Attr is a set of chattr style attributes that restrict an object regardless
+// of its permission bits and of the session's capabilities.
+type Attr uint8
+
+const (
+	// AttrImmutable forbids writing, appending, changing the permissions or
+	// ownership, and removing or renaming the object.
+	AttrImmutable Attr = 1 << iota
+	// AttrAppend only allows writes that append to the object, and forbids
+	// changing its permissions or ownership and removing or renaming it. On a
+	// directory it allows creating entries but not removing them.
+	AttrAppend
+)
+
+// String returns the attributes in lsattr style (ia).
+func (a Attr) String() string {
+	sym := []byte("--")
+	if a&AttrImmutable != 0 {
+		sym[0] = 'i'
+	}
+	if a&AttrAppend != 0 {
+		sym[1] = 'a'
+	}
+	return string(sym)
+}
+
+// Appender is implemented by Privileged objects that support appending, the
+// only kind of write allowed on append-only objects.
+type Appender interface {
+	Privileged
+	Append(...string) interface{}
+}
+
+// Attrs returns the attributes set on the rules.
+func (r *Rules) Attrs() Attr {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.attrs
+}
+
+// Chattr replaces the attributes of p. It requires CapLinuxImmutable.
+func (s *Session) Chattr(p Privileged, attrs Attr) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapLinuxImmutable) {
+		return errNotCapable
+	}
+
+	r := p.Rules()
+	r.mu.Lock()
+	r.attrs = attrs & (AttrImmutable | AttrAppend)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// CanAppend reports whether the session may append to p: it needs write
+// permission and p must not be immutable, but may be append-only.
+func (s *Session) CanAppend(p Privileged) bool {
+	return s.decide(p, OpWrite, true).Allowed
+}
+
+// Append appends to p if the session may, and p implements Appender.
+func (s *Session) Append(p Privileged, args ...string) (interface{}, error) {
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	if d := s.decide(p, OpWrite, true); !d.Allowed {
+		return nil, d.err()
+	}
+
+	a, ok := p.(Appender)
+	if !ok {
+		return nil, errNoAppend
+	}
+	return a.Append(args...), nil
+}
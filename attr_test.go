@@ -0,0 +1,91 @@
+package privileges
+
+import (
+	"testing"
+)
+
+type logfile struct {
+	r     *Rules
+	lines []string
+}
+
+func (l *logfile) Rules() *Rules                    { return l.r }
+func (l *logfile) Read(args ...string) interface{}  { return l.lines }
+func (l *logfile) Write(args ...string) interface{} { l.lines = args; return nil }
+func (l *logfile) Exec(args ...string) interface{}  { return nil }
+
+func (l *logfile) Append(args ...string) interface{} {
+	l.lines = append(l.lines, args...)
+	return nil
+}
+
+func TestAttr00(t *testing.T) {
+	p.newUser("Cyril", "Figgis")
+	defer p.deleteGroup("Cyril")
+	defer p.deleteUser("Cyril")
+
+	s, _ := p.Login("Cyril", "Figgis")
+	defer s.Logout()
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	r, _ := NewRules("Cyril", "Cyril", "0644")
+	log := &logfile{r: r}
+
+	if s.Chattr(log, AttrAppend) != errNotCapable {
+		t.Error(nil)
+	}
+
+	if su.Chattr(log, AttrAppend) != nil || r.Attrs() != AttrAppend {
+		t.Error(nil)
+	}
+
+	if _, err := s.Write(log, "erased"); err != errDenied {
+		t.Error(nil)
+	}
+
+	if _, err := s.Append(log, "one", "two"); err != nil || len(log.lines) != 2 {
+		t.Error(err)
+	}
+
+	if _, err := s.Append(r, "one"); err != errNoAppend {
+		t.Error(nil)
+	}
+
+	if s.Chmod(log, "0666") != errDenied || su.Chown(log, root) != errDenied {
+		t.Error(nil)
+	}
+
+	d := s.Explain(log, OpWrite)
+	want := `user "Cyril" requested -w-: matched owner granting rw-; denied by attributes -a`
+	if d.Allowed || d.Attr != AttrAppend || d.String() != want {
+		t.Error(d)
+	}
+
+	su.Chattr(log, AttrImmutable)
+	if su.CanWrite(log) || su.CanAppend(log) || !su.CanRead(log) {
+		t.Error(nil)
+	}
+
+	dr, _ := NewRules(root, root, "0777")
+	tmp := newFolder(dr)
+	tmp.entries["log"] = log
+	if s.Remove(tmp, "log") != errDenied {
+		t.Error(nil)
+	}
+
+	su.Chattr(log, 0)
+	su.Chattr(tmp, AttrAppend)
+	if _, err := s.Create(tmp, "new", "0644", false); err != nil {
+		t.Error(err)
+	}
+
+	if s.Remove(tmp, "log") != errDenied || s.Rename(tmp, "new", tmp, "old") != errDenied {
+		t.Error(nil)
+	}
+
+	su.Chattr(tmp, 0)
+	if s.Remove(tmp, "log") != nil {
+		t.Error(nil)
+	}
+}
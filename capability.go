@@ -0,0 +1,51 @@
+package privileges
+
+// Capability names a privilege that can be granted to users or groups without
+// making them superusers. Members of the root group hold every capability.
+type Capability string
+
+const (
+	// CapChown allows changing the owner of any object and its group to any
+	// existing group.
+	CapChown Capability = "CAP_CHOWN"
+	// CapFowner bypasses the checks that require being the owner of an object,
+	// such as chmod and the sticky bit.
+	CapFowner Capability = "CAP_FOWNER"
+	// CapDACOverride bypasses read, write and search permission checks, and
+	// execute checks on objects with at least one execute bit set.
+	CapDACOverride Capability = "CAP_DAC_OVERRIDE"
+	// CapDACReadSearch bypasses read permission checks on any object and
+	// search permission checks on directories.
+	CapDACReadSearch Capability = "CAP_DAC_READ_SEARCH"
+	// CapUserAdmin allows creating, deleting and managing users.
+	CapUserAdmin Capability = "CAP_USER_ADMIN"
+	// CapGroupAdmin allows creating and deleting groups and managing their
+	// members.
+	CapGroupAdmin Capability = "CAP_GROUP_ADMIN"
+	// CapLinuxImmutable allows setting and clearing the immutable and
+	// append-only attributes.
+	CapLinuxImmutable Capability = "CAP_LINUX_IMMUTABLE"
+	// CapMACAdmin allows setting users' clearances and objects'
+	// classifications.
+	CapMACAdmin Capability = "CAP_MAC_ADMIN"
+)
+
+var capabilities = []Capability{
+	CapChown,
+	CapFowner,
+	CapDACOverride,
+	CapDACReadSearch,
+	CapUserAdmin,
+	CapGroupAdmin,
+	CapLinuxImmutable,
+	CapMACAdmin,
+}
+
+func validCapability(c Capability) bool {
+	for _, k := range capabilities {
+		if k == c {
+			return true
+		}
+	}
+	return false
+}
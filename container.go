@@ -0,0 +1,90 @@
+package privileges
+
+// Container is implemented by directories whose entries a session can create,
+// remove and rename. The session checks permissions before calling these
+// methods; implementations only need to maintain their entries and report
+// names that don't exist or already do.
+type Container interface {
+	Directory
+	Lookup(name string) (Privileged, error)
+	Create(name string, r *Rules, directory bool) (Privileged, error)
+	Remove(name string) error
+	Rename(name string, to Container, newname string) error
+}
+
+// Create creates the entry name in dir with rules built by ChildRules. It
+// requires write and search permission on dir, which may be append-only but
+// not immutable.
+func (s *Session) Create(dir Container, name, mode string, directory bool) (Privileged, error) {
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	if !s.CanAppend(dir) || !s.CanExec(dir) {
+		return nil, errDenied
+	}
+
+	r, err := s.ChildRules(dir, mode, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir.Create(name, r, directory)
+}
+
+// Remove removes the entry name from dir. It requires write and search
+// permission on dir and, if dir has the sticky bit set, ownership of the entry
+// or of dir.
+func (s *Session) Remove(dir Container, name string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.CanExec(dir) {
+		return errDenied
+	}
+
+	p, err := dir.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if !s.CanDelete(dir, p) {
+		return errDenied
+	}
+
+	return dir.Remove(name)
+}
+
+// Rename moves the entry name in from to newname in to. Both directories need
+// write and search permission and the sticky bit rules apply to the entry and
+// to any entry it replaces. Moving a directory to a new parent also needs
+// write permission on the directory itself, as its parent link changes.
+func (s *Session) Rename(from Container, name string, to Container, newname string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.CanExec(from) || !s.CanExec(to) {
+		return errDenied
+	}
+
+	p, err := from.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if !s.CanDelete(from, p) || !s.CanAppend(to) {
+		return errDenied
+	}
+
+	if old, err := to.Lookup(newname); err == nil && !s.CanDelete(to, old) {
+		return errDenied
+	}
+
+	if isDir(p) && from.Rules() != to.Rules() && !s.CanWrite(p) {
+		return errDenied
+	}
+
+	return from.Rename(name, to, newname)
+}
@@ -0,0 +1,121 @@
+package privileges
+
+import (
+	"errors"
+	"testing"
+)
+
+var errNoEntry = errors.New("no such entry")
+
+type folder struct {
+	r       *Rules
+	entries map[string]Privileged
+}
+
+func newFolder(r *Rules) *folder {
+	return &folder{r, make(map[string]Privileged)}
+}
+
+func (f *folder) Rules() *Rules                    { return f.r }
+func (f *folder) Read(args ...string) interface{}  { return nil }
+func (f *folder) Write(args ...string) interface{} { return nil }
+func (f *folder) Exec(args ...string) interface{}  { return nil }
+func (f *folder) IsDir() bool                      { return true }
+
+func (f *folder) Lookup(name string) (Privileged, error) {
+	p, ok := f.entries[name]
+	if !ok {
+		return nil, errNoEntry
+	}
+	return p, nil
+}
+
+func (f *folder) Create(name string, r *Rules, directory bool) (Privileged, error) {
+	var p Privileged = r
+	if directory {
+		p = newFolder(r)
+	}
+	f.entries[name] = p
+	return p, nil
+}
+
+func (f *folder) Remove(name string) error {
+	delete(f.entries, name)
+	return nil
+}
+
+func (f *folder) Rename(name string, to Container, newname string) error {
+	to.(*folder).entries[newname] = f.entries[name]
+	delete(f.entries, name)
+	return nil
+}
+
+func TestContainer00(t *testing.T) {
+	p.newUser("Lana", "Kane")
+	p.newUser("Ray", "Gillette")
+	defer p.deleteGroup("Lana")
+	defer p.deleteUser("Lana")
+	defer p.deleteGroup("Ray")
+	defer p.deleteUser("Ray")
+
+	lana, _ := p.Login("Lana", "Kane")
+	defer lana.Logout()
+	ray, _ := p.Login("Ray", "Gillette")
+	defer ray.Logout()
+
+	r, _ := NewRules(root, root, "1777")
+	tmp := newFolder(r)
+	r, _ = NewRules(root, root, "0755")
+	etc := newFolder(r)
+
+	if _, err := lana.Create(etc, "passwd", "0644", false); err != errDenied {
+		t.Error(nil)
+	}
+
+	f, err := lana.Create(tmp, "notes", "0644", false)
+	if err != nil || f.Rules().Owner() != "Lana" {
+		t.Error(err)
+	}
+
+	d, err := lana.Create(tmp, "work", "0755", true)
+	if err != nil || !isDir(d) {
+		t.Error(err)
+	}
+
+	if ray.Remove(tmp, "notes") != errDenied || ray.Rename(tmp, "notes", tmp, "mine") != errDenied {
+		t.Error(nil)
+	}
+
+	if ray.Remove(tmp, "missing") != errNoEntry {
+		t.Error(nil)
+	}
+
+	if _, err = ray.Create(tmp, "mine", "0644", false); err != nil {
+		t.Error(err)
+	}
+
+	if lana.Rename(tmp, "notes", tmp, "mine") != errDenied {
+		t.Error(nil)
+	}
+
+	work := d.(*folder)
+	if lana.Rename(tmp, "notes", work, "notes") != nil {
+		t.Error(nil)
+	}
+
+	work.r.rules = 0x0555
+	if lana.Rename(tmp, "work", tmp, "play") != nil {
+		t.Error(nil)
+	}
+
+	r, _ = NewRules("Lana", "Lana", "0777")
+	home := newFolder(r)
+	if lana.Rename(tmp, "play", home, "work") != errDenied {
+		t.Error(nil)
+	}
+
+	work.r.rules = 0x0755
+	if lana.Rename(tmp, "play", home, "work") != nil || lana.Remove(home, "work") != nil {
+		t.Error(nil)
+	}
+}
@@ -0,0 +1,92 @@
+package privileges
+
+import "context"
+
+// Request carries the arguments of an operation on a PrivilegedContext. Args
+// holds the strings passed to Session.Read, Write and Exec; Body can carry any
+// structured value the object understands.
+type Request struct {
+	Op   Op
+	Args []string
+	Body interface{}
+}
+
+// PrivilegedContext is implemented by Privileged objects whose operations can
+// fail or be cancelled. The session calls these methods instead of Read, Write
+// and Exec when they are available, with itself stored in ctx.
+type PrivilegedContext interface {
+	Privileged
+	ReadContext(ctx context.Context, req *Request) (interface{}, error)
+	WriteContext(ctx context.Context, req *Request) (interface{}, error)
+	ExecContext(ctx context.Context, req *Request) (interface{}, error)
+}
+
+type sessionKey struct{}
+
+// NewContext returns a copy of ctx carrying the session s.
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext returns the session stored in ctx, if any.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// ReadContext reads p with req if the session may.
+func (s *Session) ReadContext(ctx context.Context, p Privileged, req *Request) (interface{}, error) {
+	return s.dispatch(ctx, p, OpRead, req)
+}
+
+// WriteContext writes p with req if the session may.
+func (s *Session) WriteContext(ctx context.Context, p Privileged, req *Request) (interface{}, error) {
+	return s.dispatch(ctx, p, OpWrite, req)
+}
+
+// ExecContext executes p with req if the session may.
+func (s *Session) ExecContext(ctx context.Context, p Privileged, req *Request) (interface{}, error) {
+	return s.dispatch(ctx, p, OpExec, req)
+}
+
+// dispatch checks the session's access to p for op and calls whichever of
+// PrivilegedContext and Privileged p implements.
+func (s *Session) dispatch(ctx context.Context, p Privileged, op Op, req *Request) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	if d := s.Explain(p, op); !d.Allowed {
+		return nil, d.err()
+	}
+
+	if req == nil {
+		req = new(Request)
+	}
+	req.Op = op
+
+	if pc, ok := p.(PrivilegedContext); ok {
+		ctx = NewContext(ctx, s)
+		switch op {
+		case OpRead:
+			return pc.ReadContext(ctx, req)
+		case OpWrite:
+			return pc.WriteContext(ctx, req)
+		default:
+			return pc.ExecContext(ctx, req)
+		}
+	}
+
+	switch op {
+	case OpRead:
+		return p.Read(req.Args...), nil
+	case OpWrite:
+		return p.Write(req.Args...), nil
+	default:
+		return p.Exec(req.Args...), nil
+	}
+}
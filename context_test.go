@@ -0,0 +1,81 @@
+package privileges
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var errEmpty = errors.New("empty report")
+
+type report struct {
+	r    *Rules
+	body string
+}
+
+func (o *report) Rules() *Rules                    { return o.r }
+func (o *report) Read(args ...string) interface{}  { return "legacy" }
+func (o *report) Write(args ...string) interface{} { return "legacy" }
+func (o *report) Exec(args ...string) interface{}  { return "legacy" }
+
+func (o *report) ReadContext(ctx context.Context, req *Request) (interface{}, error) {
+	s, ok := FromContext(ctx)
+	if !ok {
+		return nil, errBadSession
+	}
+	return s.User + ":" + o.body, nil
+}
+
+func (o *report) WriteContext(ctx context.Context, req *Request) (interface{}, error) {
+	body, _ := req.Body.(string)
+	if body == "" {
+		return nil, errEmpty
+	}
+	o.body = body
+	return nil, nil
+}
+
+func (o *report) ExecContext(ctx context.Context, req *Request) (interface{}, error) {
+	return req.Args, nil
+}
+
+func TestContext00(t *testing.T) {
+	p.newUser("Pam", "Poovey")
+	defer p.deleteGroup("Pam")
+	defer p.deleteUser("Pam")
+
+	s, _ := p.Login("Pam", "Poovey")
+	defer s.Logout()
+
+	r, _ := NewRules("Pam", "Pam", "0640")
+	o := &report{r: r, body: "draft"}
+	ctx := context.Background()
+
+	v, err := s.Read(o)
+	if err != nil || v != "Pam:draft" {
+		t.Error(v, err)
+	}
+
+	if _, err = s.WriteContext(ctx, o, &Request{}); err != errEmpty {
+		t.Error(nil)
+	}
+
+	if _, err = s.WriteContext(ctx, o, &Request{Body: "final"}); err != nil || o.body != "final" {
+		t.Error(err)
+	}
+
+	if _, err = s.ExecContext(ctx, o, nil); err != errDenied {
+		t.Error(nil)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = s.ReadContext(cancelled, o, nil); err != context.Canceled {
+		t.Error(nil)
+	}
+
+	v, err = s.ReadContext(ctx, r, nil)
+	if err != nil || v != nil {
+		t.Error(nil)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"io/ioutil"
+	"time"
 
 	sqlite3 "github.com/mattn/go-sqlite3"
 )
@@ -16,34 +17,64 @@ var (
 )
 
 type Privileges struct {
-	sessions map[string]bool
-	db       *sql.DB
-	path     string
+	sessions    map[string]*Session
+	db          *sql.DB
+	path        string
+	dacOverride bool
+	mac         bool
+	systemIDs   IDRange
+	regularIDs  IDRange
 }
 
 type record struct {
-	name  string
-	salt  string
-	pass  string
-	gid   string
-	umask string
+	name      string
+	salt      string
+	pass      string
+	gid       string
+	umask     string
+	clearance string
+	locked    bool
+	disabled  bool
+	expires   int64
+	aging     PasswordAging
 }
 
 func New(path string) (*Privileges, error) {
 
 	p := new(Privileges)
 	p.path = path
+	p.systemIDs = defaultSystemIDs
+	p.regularIDs = defaultRegularIDs
 	p.db, _ = sql.Open("sqlite3_fk", p.path)
 	err := p.setup()
 	if err != nil {
 		return p, err
 	}
-	p.sessions = make(map[string]bool)
+	p.sessions = make(map[string]*Session)
+	p.dacOverride = true
 
 	return p, nil
 
 }
 
+// SetDACOverride controls whether superusers bypass the permission bits of
+// objects when reading, writing and executing them. It is enabled by default;
+// strict deployments can disable it so superusers are checked like any other
+// user.
+func (p *Privileges) SetDACOverride(enabled bool) {
+
+	p.dacOverride = enabled
+
+}
+
+// SetMAC controls whether the mandatory access control rules are enforced on
+// top of the permission bits. It is disabled by default.
+func (p *Privileges) SetMAC(enabled bool) {
+
+	p.mac = enabled
+
+}
+
 func (p *Privileges) Close() {
 
 	p.db.Close()
@@ -65,11 +96,11 @@ func (p *Privileges) Restore(snapshot []byte) error {
 
 }
 
+// Login logs a user in. If the user's password has expired the session is
+// restricted until it changes it; see Session.MustChangePassword.
 func (p *Privileges) Login(username, password string) (*Session, error) {
 
-	rec := new(record)
-	row := p.db.QueryRow("SELECT * FROM users WHERE name=?", username)
-	err := row.Scan(&rec.name, &rec.salt, &rec.pass, &rec.gid, &rec.umask)
+	rec, err := p.record(username)
 	if err != nil {
 		return nil, errBadCredentials
 	}
@@ -79,26 +110,19 @@ func (p *Privileges) Login(username, password string) (*Session, error) {
 		return nil, errBadCredentials
 	}
 
-	s := new(Session)
-	s.p = p
-	s.User = username
-	s.Hash = hashword
-	s.gid = rec.gid
-	s.umask = rec.umask
-	s.SID = string(GenerateSalt64())
-	s.groups, _ = p.userListGroups(username)
-	s.su, _ = p.inGroup(username, root)
-	p.sessions[s.SID] = true
+	if err = rec.status(); err != nil {
+		return nil, err
+	}
 
+	s := p.newSession(rec, hashword)
+	s.restricted = rec.aging.Expired()
 	return s, nil
 
 }
 
 func (p *Privileges) LoginHash(username, hashword string) (*Session, error) {
 
-	rec := new(record)
-	row := p.db.QueryRow("SELECT * FROM users WHERE name=?", username)
-	err := row.Scan(&rec.name, &rec.salt, &rec.pass, &rec.gid, &rec.umask)
+	rec, err := p.record(username)
 	if err != nil {
 		return nil, errBadCredentials
 	}
@@ -107,18 +131,87 @@ func (p *Privileges) LoginHash(username, hashword string) (*Session, error) {
 		return nil, errBadCredentials
 	}
 
+	if err = rec.status(); err != nil {
+		return nil, err
+	}
+
+	s := p.newSession(rec, hashword)
+	s.restricted = rec.aging.Expired()
+	return s, nil
+
+}
+
+// CheckAccess reports whether the user could access obj with the bits in op,
+// without logging the user in. It runs the same checks as Session.CanRead,
+// CanWrite and CanExec with the user's groups and capabilities.
+func (p *Privileges) CheckAccess(username string, obj Privileged, op Op) (bool, error) {
+
+	d, err := p.ExplainAccess(username, obj, op)
+	if err != nil {
+		return false, err
+	}
+	return d.Allowed, nil
+
+}
+
+// ExplainAccess is like CheckAccess but reports how the decision was made.
+func (p *Privileges) ExplainAccess(username string, obj Privileged, op Op) (*Decision, error) {
+
+	rec, err := p.record(username)
+	if err != nil {
+		return nil, err
+	}
+	return p.subject(rec).Explain(obj, op), nil
+
+}
+
+func (p *Privileges) newSession(rec *record, hashword string) *Session {
+
+	s := p.subject(rec)
+	s.Hash = hashword
+	s.SID = string(GenerateSalt64())
+	p.sessions[s.SID] = s
+
+	return s
+
+}
+
+// subject returns a session describing the user in rec that is not logged in,
+// for use in access checks.
+func (p *Privileges) subject(rec *record) *Session {
+
 	s := new(Session)
 	s.p = p
-	s.User = username
-	s.Hash = hashword
+	s.User = rec.name
 	s.gid = rec.gid
 	s.umask = rec.umask
-	s.SID = string(GenerateSalt64())
-	s.groups, _ = p.userListGroups(username)
-	s.su, _ = p.inGroup(username, root)
-	p.sessions[s.SID] = true
+	s.expires = rec.expires
+	s.clearance, _ = ParseLabel(rec.clearance)
+	s.groups, _ = p.userListGroups(rec.name)
+	s.su, _ = p.inGroup(rec.name, root)
+	s.caps = make(map[Capability]bool)
+	caps, _ := p.userCapabilities(rec.name)
+	for _, c := range caps {
+		s.caps[c] = true
+	}
 
-	return s, nil
+	return s
+
+}
+
+func (p *Privileges) record(username string) (*record, error) {
+
+	rec := new(record)
+	var lastchange, minage, maxage, warn int64
+	row := p.db.QueryRow("SELECT name, salt, pass, gid, umask, clearance, locked, disabled, expires, "+
+		"lastchange, minage, maxage, warn, mustchange FROM users WHERE name=?", username)
+	err := row.Scan(&rec.name, &rec.salt, &rec.pass, &rec.gid, &rec.umask, &rec.clearance, &rec.locked, &rec.disabled, &rec.expires,
+		&lastchange, &minage, &maxage, &warn, &rec.aging.MustChange)
+	rec.aging.LastChange = time.Unix(lastchange, 0)
+	rec.aging.MinAge = time.Duration(minage) * time.Second
+	rec.aging.MaxAge = time.Duration(maxage) * time.Second
+	rec.aging.Warn = time.Duration(warn) * time.Second
+	return rec, err
 
 }
 
@@ -180,6 +273,10 @@ func (p *Privileges) setup() error {
 
 	p.createUsersTable()
 	p.createUsersGroupsTable()
+	p.createCapabilitiesTables()
+	p.createUserAttrsTable()
+	p.createObjectsTable()
+	p.assignIDs()
 	p.createStandardEntries()
 
 	return nil
@@ -189,7 +286,16 @@ func (p *Privileges) setup() error {
 func (p *Privileges) createGroupsTable() error {
 
 	_, err := p.db.Exec("CREATE TABLE IF NOT EXISTS groups (name VARCHAR(64) PRIMARY KEY);")
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the table was introduced. Adding them fails
+	// harmlessly once they exist.
+	p.db.Exec("ALTER TABLE groups ADD COLUMN gid INTEGER NULL;")
+	p.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS groupsgid ON groups(gid);")
+
+	return nil
 
 }
 
@@ -204,6 +310,38 @@ func (p *Privileges) createUsersTable() {
 		"FOREIGN KEY (gid) REFERENCES groups(name)" +
 		");")
 
+	// Columns added after the table was introduced. Adding them fails
+	// harmlessly once they exist.
+	p.db.Exec("ALTER TABLE users ADD COLUMN clearance TEXT NOT NULL DEFAULT '0';")
+	p.db.Exec("ALTER TABLE users ADD COLUMN uid INTEGER NULL;")
+	p.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS usersuid ON users(uid);")
+	p.db.Exec("ALTER TABLE users ADD COLUMN fullname TEXT NOT NULL DEFAULT '';")
+	p.db.Exec("ALTER TABLE users ADD COLUMN home TEXT NOT NULL DEFAULT '';")
+	p.db.Exec("ALTER TABLE users ADD COLUMN shell TEXT NOT NULL DEFAULT '';")
+	p.db.Exec("ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';")
+	p.db.Exec("ALTER TABLE users ADD COLUMN locked INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN expires INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN lastchange INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN minage INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN maxage INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN warn INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE users ADD COLUMN mustchange INTEGER NOT NULL DEFAULT 0;")
+
+	// Passwords set before aging was introduced count as changed now.
+	p.db.Exec("UPDATE users SET lastchange=? WHERE lastchange=0", time.Now().Unix())
+
+	// Umasks used to be stored without being applied, with 0775 as the
+	// default. Now that they clear permission bits that default would strip
+	// nearly everything, so it is replaced by 0002 once; user_version records
+	// that the migration ran.
+	var version int
+	p.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version < 1 {
+		p.db.Exec("UPDATE users SET umask='0002' WHERE umask='0775'")
+		p.db.Exec("PRAGMA user_version=1")
+	}
+
 }
 
 func (p *Privileges) createUsersGroupsTable() {
@@ -218,37 +356,78 @@ func (p *Privileges) createUsersGroupsTable() {
 
 }
 
+func (p *Privileges) createCapabilitiesTables() {
+
+	p.db.Exec("CREATE TABLE IF NOT EXISTS userscaps (" +
+		"username VARCHAR(64) NULL, " +
+		"capability VARCHAR(32) NULL, " +
+		"PRIMARY KEY (username, capability), " +
+		"FOREIGN KEY (username) REFERENCES users(name) ON DELETE CASCADE" +
+		");")
+
+	p.db.Exec("CREATE TABLE IF NOT EXISTS groupscaps (" +
+		"groupname VARCHAR(64) NULL, " +
+		"capability VARCHAR(32) NULL, " +
+		"PRIMARY KEY (groupname, capability), " +
+		"FOREIGN KEY (groupname) REFERENCES groups(name) ON DELETE CASCADE" +
+		");")
+
+}
+
 func (p *Privileges) createStandardEntries() {
 
-	p.newUser(root, rootPassword)
+	p.newUserID(root, rootPassword, 0)
 	p.newUser("guest", "")
 
 }
 
 func (p *Privileges) newGroup(name string) error {
 
+	return p.newGroupID(name, AutoID)
+
+}
+
+func (p *Privileges) newGroupID(name string, id int) error {
+
 	if name == "" {
 		return errBadName
 	}
 
-	_, err := p.db.Exec("INSERT INTO groups(name) VALUES(?)", name)
+	gid, err := p.allocID(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec("INSERT INTO groups(name, gid) VALUES(?, ?)", name, gid)
 	return err
 
 }
 
 func (p *Privileges) newUser(username, password string) error {
 
+	return p.newUserID(username, password, AutoID)
+
+}
+
+// newUserID creates a user and its personal group, both with the same id.
+func (p *Privileges) newUserID(username, password string, id int) error {
+
 	if username == "" {
 		return errBadName
 	}
 
-	err := p.newGroup(username)
+	uid, err := p.allocID(id)
+	if err != nil {
+		return err
+	}
+
+	err = p.newGroupID(username, uid)
 	if err != nil {
 		return err
 	}
 
 	salt, hash := saltAndHash(password)
-	p.db.Exec("INSERT INTO users(name, salt, pass, gid, umask) VALUES(?, ?, ?, ?, ?)", username, salt, hash, username, "0775")
+	p.db.Exec("INSERT INTO users(name, salt, pass, gid, umask, uid, lastchange) VALUES(?, ?, ?, ?, ?, ?, ?)", username, salt, hash, username, "0002", uid, time.Now().Unix())
 	p.addToGroup(username, username)
 	return nil
 
@@ -256,6 +435,12 @@ func (p *Privileges) newUser(username, password string) error {
 
 func (p *Privileges) newUserHash(username, salt, hashword string) error {
 
+	return p.newUserHashID(username, salt, hashword, AutoID)
+
+}
+
+func (p *Privileges) newUserHashID(username, salt, hashword string, id int) error {
+
 	if username == "" {
 		return errBadName
 	}
@@ -268,12 +453,17 @@ func (p *Privileges) newUserHash(username, salt, hashword string) error {
 		return errBadHash
 	}
 
-	err := p.newGroup(username)
+	uid, err := p.allocID(id)
+	if err != nil {
+		return err
+	}
+
+	err = p.newGroupID(username, uid)
 	if err != nil {
 		return err
 	}
 
-	p.db.Exec("INSERT INTO users(name, salt, pass, gid, umask) VALUES(?, ?, ?, ?, ?)", username, salt, hashword, username, "0002")
+	p.db.Exec("INSERT INTO users(name, salt, pass, gid, umask, uid, lastchange) VALUES(?, ?, ?, ?, ?, ?, ?)", username, salt, hashword, username, "0002", uid, time.Now().Unix())
 	p.addToGroup(username, username)
 	return nil
 
@@ -281,9 +471,7 @@ func (p *Privileges) newUserHash(username, salt, hashword string) error {
 
 func (p *Privileges) changePassword(username, salt, hashword string) error {
 
-	row := p.db.QueryRow("SELECT * FROM users WHERE name=?", username)
-	rec := new(record)
-	err := row.Scan(&rec.name, &rec.salt, &rec.pass, &rec.gid, &rec.umask)
+	_, err := p.record(username)
 	if err != nil {
 		return err
 	}
@@ -296,7 +484,7 @@ func (p *Privileges) changePassword(username, salt, hashword string) error {
 		return errBadHash
 	}
 
-	_, err = p.db.Exec("UPDATE users SET salt=?, pass=? WHERE name=?", salt, hashword, username)
+	_, err = p.db.Exec("UPDATE users SET salt=?, pass=?, lastchange=?, mustchange=0 WHERE name=?", salt, hashword, time.Now().Unix(), username)
 	return err
 
 }
@@ -308,6 +496,86 @@ func (p *Privileges) addToGroup(user, group string) error {
 
 }
 
+func (p *Privileges) grantUserCapability(username string, c Capability) error {
+
+	if !validCapability(c) {
+		return errBadCapability
+	}
+
+	_, err := p.db.Exec("INSERT OR IGNORE INTO userscaps(username, capability) VALUES(?, ?)", username, string(c))
+	return err
+
+}
+
+func (p *Privileges) revokeUserCapability(username string, c Capability) error {
+
+	_, err := p.db.Exec("DELETE FROM userscaps WHERE username=? AND capability=?", username, string(c))
+	return err
+
+}
+
+func (p *Privileges) grantGroupCapability(group string, c Capability) error {
+
+	if !validCapability(c) {
+		return errBadCapability
+	}
+
+	_, err := p.db.Exec("INSERT OR IGNORE INTO groupscaps(groupname, capability) VALUES(?, ?)", group, string(c))
+	return err
+
+}
+
+func (p *Privileges) revokeGroupCapability(group string, c Capability) error {
+
+	_, err := p.db.Exec("DELETE FROM groupscaps WHERE groupname=? AND capability=?", group, string(c))
+	return err
+
+}
+
+// userCapabilities returns the capabilities granted to the user directly or
+// through any of its groups.
+func (p *Privileges) userCapabilities(username string) ([]Capability, error) {
+
+	var caps []Capability
+
+	rows, err := p.db.Query("SELECT capability FROM userscaps WHERE username=? "+
+		"UNION SELECT capability FROM groupscaps WHERE groupname IN "+
+		"(SELECT groupname FROM usersgroups WHERE username=?)", username, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var c string
+	for rows.Next() {
+		rows.Scan(&c)
+		caps = append(caps, Capability(c))
+	}
+
+	return caps, nil
+
+}
+
+func (p *Privileges) groupCapabilities(group string) ([]Capability, error) {
+
+	var caps []Capability
+
+	rows, err := p.db.Query("SELECT capability FROM groupscaps WHERE groupname=?", group)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var c string
+	for rows.Next() {
+		rows.Scan(&c)
+		caps = append(caps, Capability(c))
+	}
+
+	return caps, nil
+
+}
+
 func (p *Privileges) inGroup(username, group string) (bool, error) {
 
 	var x string
@@ -403,6 +671,20 @@ func (p *Privileges) gid(username string) (string, error) {
 
 }
 
+func (p *Privileges) setClearance(username string, l Label) error {
+
+	res, err := p.db.Exec("UPDATE users SET clearance=? WHERE name=?", l.String(), username)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBadName
+	}
+	return nil
+
+}
+
 func (p *Privileges) setUmask(mask, username string) error {
 
 	if !validRules(mask) {
@@ -270,3 +270,90 @@ func TestDB014(t *testing.T) {
 		t.Error(nil)
 	}
 }
+
+func TestDB015(t *testing.T) {
+	s, err := p.Login(root, rootPassword)
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	r, _ := NewRules("guest", "guest", "0000")
+	if !s.CanRead(r) || !s.CanWrite(r) || s.CanExec(r) {
+		t.Error(nil)
+	}
+
+	if !s.CanExec(dir{r}) {
+		t.Error(nil)
+	}
+
+	r, _ = NewRules("guest", "guest", "0001")
+	if !s.CanExec(r) {
+		t.Error(nil)
+	}
+
+	p.SetDACOverride(false)
+	defer p.SetDACOverride(true)
+
+	if s.CanRead(r) || s.CanWrite(r) || !s.CanExec(r) {
+		t.Error(nil)
+	}
+}
+
+func TestDB016(t *testing.T) {
+	p.newUser("Ray", "Gillette")
+	defer p.deleteGroup("Ray")
+	defer p.deleteUser("Ray")
+
+	r, _ := NewRules("Ray", root, "0640")
+
+	ok, err := p.CheckAccess("Ray", r, OpRead|OpWrite)
+	if err != nil || !ok {
+		t.Error(nil)
+	}
+
+	ok, err = p.CheckAccess("guest", r, OpRead)
+	if err != nil || ok {
+		t.Error(nil)
+	}
+
+	ok, err = p.CheckAccess(root, r, OpWrite)
+	if err != nil || !ok {
+		t.Error(nil)
+	}
+
+	_, err = p.CheckAccess("nobody", r, OpRead)
+	if err == nil {
+		t.Error(nil)
+	}
+
+	d, err := p.ExplainAccess(root, r, OpRead)
+	if err != nil || d.Class != ClassGroup || d.Group != root || !d.Allowed {
+		t.Error(d)
+	}
+}
+
+func TestDB017(t *testing.T) {
+	p.newUser("Pam", "Poovey")
+	defer p.deleteGroup("Pam")
+	defer p.deleteUser("Pam")
+
+	// A database from before umasks were applied.
+	p.db.Exec("UPDATE users SET umask='0775' WHERE name=?", "Pam")
+	p.db.Exec("PRAGMA user_version=0")
+	p.createUsersTable()
+
+	rec, _ := p.record("Pam")
+	if rec.umask != "0002" {
+		t.Error(rec.umask)
+	}
+
+	// It only runs once.
+	p.setUmask("0775", "Pam")
+	p.createUsersTable()
+
+	rec, _ = p.record("Pam")
+	if rec.umask != "0775" {
+		t.Error(rec.umask)
+	}
+}
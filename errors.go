@@ -3,14 +3,37 @@ package privileges
 import "errors"
 
 var (
-	errGroupHasGids   = errors.New("can't delete group because it is gid for users")
-	errRoot           = errors.New("can't perform this operation on root")
-	errBadHash        = errors.New("bad hash")
-	errBadSalt        = errors.New("bad salt")
-	errBadName        = errors.New("bad group or user name")
-	errDenied         = errors.New("access denied")
-	errNotSU          = errors.New("only a superuser may perform this action")
-	errBadRulesString = errors.New("bad rules string")
-	errBadCredentials = errors.New("invalid username or password")
-	errBadSession     = errors.New("invalid privileges session")
+	errGroupHasGids    = errors.New("can't delete group because it is gid for users")
+	errRoot            = errors.New("can't perform this operation on root")
+	errBadHash         = errors.New("bad hash")
+	errBadSalt         = errors.New("bad salt")
+	errBadName         = errors.New("bad group or user name")
+	errDenied          = errors.New("access denied")
+	errDeniedMAC       = errors.New("access denied by mandatory access control")
+	errNotSU           = errors.New("only a superuser may perform this action")
+	errNotCapable      = errors.New("session lacks the capability for this action")
+	errBadCapability   = errors.New("unknown capability")
+	errBadRulesString  = errors.New("bad rules string")
+	errBadModeString   = errors.New("bad mode expression")
+	errBadSymbolic     = errors.New("bad symbolic string")
+	errACLMissing      = errors.New("extended access control list missing")
+	errBadACL          = errors.New("bad access control list")
+	errBadPath         = errors.New("bad path")
+	errNoObject        = errors.New("no rules stored for path")
+	errNoAppend        = errors.New("object does not support appending")
+	errBadRequest      = errors.New("request body has the wrong type")
+	errBadVerb         = errors.New("unknown or malformed verb")
+	errBadLabel        = errors.New("bad security label")
+	errBadCredentials  = errors.New("invalid username or password")
+	errBadSession      = errors.New("invalid privileges session")
+	errBadID           = errors.New("bad or unknown user or group id")
+	errBadIDRange      = errors.New("bad id range")
+	errIDInUse         = errors.New("id already in use")
+	errNoFreeID        = errors.New("no free id left in range")
+	errBadField        = errors.New("bad profile field")
+	errAccountLocked   = errors.New("account is locked")
+	errAccountDisabled = errors.New("account is disabled")
+	errAccountExpired  = errors.New("account has expired")
+	errPasswordExpired = errors.New("password has expired and must be changed")
+	errPasswordTooNew  = errors.New("password was changed too recently")
 )
@@ -0,0 +1,131 @@
+package privileges
+
+import (
+	"context"
+	"sync"
+)
+
+// Guarded pairs a value with the Rules protecting it, so a resource type
+// doesn't need its own Privileged implementation. Get, Set and Call check the
+// session's read, write and execute permission respectively; Session.Read,
+// Write and Exec reach the same operations through PrivilegedContext.
+type Guarded[T any] struct {
+	mu    sync.RWMutex
+	rules *Rules
+	value T
+}
+
+// NewGuarded returns value guarded by r.
+func NewGuarded[T any](value T, r *Rules) *Guarded[T] {
+	return &Guarded[T]{rules: r, value: value}
+}
+
+func (g *Guarded[T]) Rules() *Rules {
+	return g.rules
+}
+
+// Get returns the value if the session may read it.
+func (g *Guarded[T]) Get(s *Session) (T, error) {
+	var zero T
+	if !s.valid() {
+		return zero, errBadSession
+	}
+
+	if d := s.Explain(g, OpRead); !d.Allowed {
+		return zero, d.err()
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.value, nil
+}
+
+// Set replaces the value if the session may write it.
+func (g *Guarded[T]) Set(s *Session, value T) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if d := s.Explain(g, OpWrite); !d.Allowed {
+		return d.err()
+	}
+
+	g.mu.Lock()
+	g.value = value
+	g.mu.Unlock()
+	return nil
+}
+
+// Call runs fn on the value if the session may execute it. fn may modify the
+// value; no other operation on it runs concurrently.
+func (g *Guarded[T]) Call(s *Session, fn func(*T) error) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if d := s.Explain(g, OpExec); !d.Allowed {
+		return d.err()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return fn(&g.value)
+}
+
+// Read, Write and Exec do nothing and return nil, to satisfy Privileged; the
+// value is only reached through the checked methods and the context methods.
+func (g *Guarded[T]) Read(args ...string) interface{} {
+	return nil
+}
+
+func (g *Guarded[T]) Write(args ...string) interface{} {
+	return nil
+}
+
+func (g *Guarded[T]) Exec(args ...string) interface{} {
+	return nil
+}
+
+// ReadContext returns the value if the session in ctx may read it.
+func (g *Guarded[T]) ReadContext(ctx context.Context, req *Request) (interface{}, error) {
+	s, ok := FromContext(ctx)
+	if !ok {
+		return nil, errBadSession
+	}
+
+	value, err := g.Get(s)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// WriteContext replaces the value with req.Body, which must be a T, if the
+// session in ctx may write it.
+func (g *Guarded[T]) WriteContext(ctx context.Context, req *Request) (interface{}, error) {
+	s, ok := FromContext(ctx)
+	if !ok {
+		return nil, errBadSession
+	}
+
+	value, ok := req.Body.(T)
+	if !ok {
+		return nil, errBadRequest
+	}
+	return nil, g.Set(s, value)
+}
+
+// ExecContext runs req.Body, which must be a func(*T) error, on the value if
+// the session in ctx may execute it.
+func (g *Guarded[T]) ExecContext(ctx context.Context, req *Request) (interface{}, error) {
+	s, ok := FromContext(ctx)
+	if !ok {
+		return nil, errBadSession
+	}
+
+	fn, ok := req.Body.(func(*T) error)
+	if !ok {
+		return nil, errBadRequest
+	}
+	return nil, g.Call(s, fn)
+}
@@ -0,0 +1,75 @@
+package privileges
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGuarded00(t *testing.T) {
+	p.newUser("Ray", "Gillette")
+	defer p.deleteGroup("Ray")
+	defer p.deleteUser("Ray")
+
+	ray, _ := p.Login("Ray", "Gillette")
+	defer ray.Logout()
+	guest, _ := p.Login("guest", "")
+	defer guest.Logout()
+
+	r, _ := NewRules("Ray", "Ray", "0744")
+	g := NewGuarded(3, r)
+
+	if v, err := g.Get(guest); err != nil || v != 3 {
+		t.Error(v, err)
+	}
+
+	if g.Set(guest, 4) != errDenied || g.Call(guest, func(v *int) error { return nil }) != errDenied {
+		t.Error(nil)
+	}
+
+	if g.Set(ray, 4) != nil {
+		t.Error(nil)
+	}
+
+	if g.Call(ray, func(v *int) error { *v *= 10; return nil }) != nil {
+		t.Error(nil)
+	}
+
+	if v, err := guest.Read(g); err != nil || v != 40 {
+		t.Error(v, err)
+	}
+
+	ctx := context.Background()
+	if _, err := ray.WriteContext(ctx, g, &Request{Body: "five"}); err != errBadRequest {
+		t.Error(nil)
+	}
+
+	if _, err := ray.WriteContext(ctx, g, &Request{Body: 5}); err != nil {
+		t.Error(nil)
+	}
+
+	inc := func(v *int) error { *v++; return nil }
+	if _, err := ray.ExecContext(ctx, g, &Request{Body: inc}); err != nil {
+		t.Error(nil)
+	}
+
+	if v, _ := g.Get(ray); v != 6 {
+		t.Error(v)
+	}
+
+	// The context methods check the session carried by the context.
+	if _, err := g.WriteContext(ctx, &Request{Body: 42}); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := g.WriteContext(NewContext(ctx, guest), &Request{Body: 42}); err != errDenied {
+		t.Error(err)
+	}
+
+	if v := g.Read(); v != nil {
+		t.Error(v)
+	}
+
+	if v, _ := g.Get(ray); v != 6 {
+		t.Error(v)
+	}
+}
@@ -0,0 +1,266 @@
+package privileges
+
+import "database/sql"
+
+// IDRange is an inclusive range of numeric user and group ids.
+type IDRange struct {
+	Min int
+	Max int
+}
+
+// Sentinels passed instead of an explicit id to have one allocated.
+const (
+	AutoID       = -1 // the lowest free id in the regular range
+	AutoSystemID = -2 // the lowest free id in the system range
+)
+
+// Default id ranges. Id 0 belongs to root.
+var (
+	defaultSystemIDs  = IDRange{1, 999}
+	defaultRegularIDs = IDRange{1000, 59999}
+)
+
+func (r IDRange) valid() bool {
+	return r.Min > 0 && r.Min <= r.Max
+}
+
+func (r IDRange) overlaps(o IDRange) bool {
+	return r.Min <= o.Max && o.Min <= r.Max
+}
+
+// SetIDRanges sets the ranges that automatically allocated ids are taken
+// from. Ids already assigned are left alone.
+func (p *Privileges) SetIDRanges(system, regular IDRange) error {
+
+	if !system.valid() || !regular.valid() || system.overlaps(regular) {
+		return errBadIDRange
+	}
+
+	p.systemIDs = system
+	p.regularIDs = regular
+	return nil
+
+}
+
+// allocID resolves id to the id to assign to a new user or group. Explicit ids
+// must not be taken by any user or group, so that a user and its personal
+// group can share one; AutoID and AutoSystemID allocate the lowest id free in
+// both tables.
+func (p *Privileges) allocID(id int) (int, error) {
+
+	var r IDRange
+	switch {
+	case id == AutoID:
+		r = p.regularIDs
+	case id == AutoSystemID:
+		r = p.systemIDs
+	case id < 0:
+		return 0, errBadID
+	default:
+		if p.idTaken(id) {
+			return 0, errIDInUse
+		}
+		return id, nil
+	}
+
+	rows, err := p.db.Query("SELECT uid FROM users WHERE uid BETWEEN ? AND ? "+
+		"UNION SELECT gid FROM groups WHERE gid BETWEEN ? AND ? ORDER BY 1", r.Min, r.Max, r.Min, r.Max)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	next := r.Min
+	var used int
+	for rows.Next() {
+		rows.Scan(&used)
+		if used != next {
+			break
+		}
+		next++
+	}
+
+	if next > r.Max {
+		return 0, errNoFreeID
+	}
+	return next, nil
+
+}
+
+func (p *Privileges) idTaken(id int) bool {
+
+	var x int
+	row := p.db.QueryRow("SELECT uid FROM users WHERE uid=? UNION SELECT gid FROM groups WHERE gid=?", id, id)
+	return row.Scan(&x) == nil
+
+}
+
+// assignIDs gives ids to the users and groups created before ids were
+// introduced: root gets 0 and the rest are allocated from the regular range
+// in name order.
+func (p *Privileges) assignIDs() {
+
+	p.db.Exec("UPDATE users SET uid=0 WHERE name=? AND uid IS NULL", root)
+	p.db.Exec("UPDATE groups SET gid=0 WHERE name=? AND gid IS NULL", root)
+
+	for _, table := range []string{"users", "groups"} {
+		column := "uid"
+		if table == "groups" {
+			column = "gid"
+		}
+
+		names, err := p.listNames("SELECT name FROM " + table + " WHERE " + column + " IS NULL ORDER BY name")
+		if err != nil {
+			return
+		}
+
+		for _, name := range names {
+			// A user's personal group is given the user's id when free.
+			var id sql.NullInt64
+			if table == "groups" {
+				p.db.QueryRow("SELECT uid FROM users WHERE name=?", name).Scan(&id)
+			}
+
+			n := int(id.Int64)
+			if !id.Valid || p.idTaken(n) {
+				if n, err = p.allocID(AutoID); err != nil {
+					return
+				}
+			}
+			p.db.Exec("UPDATE "+table+" SET "+column+"=? WHERE name=?", n, name)
+		}
+	}
+
+}
+
+func (p *Privileges) listNames(query string, args ...interface{}) ([]string, error) {
+
+	var names []string
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var name string
+	for rows.Next() {
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+
+	return names, nil
+
+}
+
+func (p *Privileges) userID(username string) (int, error) {
+
+	var uid int
+	err := p.db.QueryRow("SELECT uid FROM users WHERE name=?", username).Scan(&uid)
+	if err == sql.ErrNoRows {
+		return 0, errBadName
+	}
+	return uid, err
+
+}
+
+func (p *Privileges) userByID(uid int) (string, error) {
+
+	var name string
+	err := p.db.QueryRow("SELECT name FROM users WHERE uid=?", uid).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", errBadID
+	}
+	return name, err
+
+}
+
+func (p *Privileges) groupID(group string) (int, error) {
+
+	var gid int
+	err := p.db.QueryRow("SELECT gid FROM groups WHERE name=?", group).Scan(&gid)
+	if err == sql.ErrNoRows {
+		return 0, errBadName
+	}
+	return gid, err
+
+}
+
+func (p *Privileges) groupByID(gid int) (string, error) {
+
+	var name string
+	err := p.db.QueryRow("SELECT name FROM groups WHERE gid=?", gid).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", errBadID
+	}
+	return name, err
+
+}
+
+// NewRulesByID is like NewRules but takes the owner and group by id.
+func (p *Privileges) NewRulesByID(uid, gid int, rules string) (*Rules, error) {
+
+	owner, err := p.userByID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := p.groupByID(gid)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRules(owner, group, rules)
+
+}
+
+// RulesIDs returns the ids of the owner and group of r.
+func (p *Privileges) RulesIDs(r *Rules) (uid, gid int, err error) {
+
+	if uid, err = p.userID(r.Owner()); err != nil {
+		return 0, 0, err
+	}
+
+	if gid, err = p.groupID(r.Group()); err != nil {
+		return 0, 0, err
+	}
+
+	return uid, gid, nil
+
+}
+
+// UserID returns the uid of a user.
+func (s *Session) UserID(username string) (int, error) {
+	if !s.valid() {
+		return 0, errBadSession
+	}
+
+	return s.p.userID(username)
+}
+
+// UserByID returns the name of the user with the given uid.
+func (s *Session) UserByID(uid int) (string, error) {
+	if !s.valid() {
+		return "", errBadSession
+	}
+
+	return s.p.userByID(uid)
+}
+
+// GroupID returns the gid of a group.
+func (s *Session) GroupID(group string) (int, error) {
+	if !s.valid() {
+		return 0, errBadSession
+	}
+
+	return s.p.groupID(group)
+}
+
+// GroupByID returns the name of the group with the given gid.
+func (s *Session) GroupByID(gid int) (string, error) {
+	if !s.valid() {
+		return "", errBadSession
+	}
+
+	return s.p.groupByID(gid)
+}
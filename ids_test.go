@@ -0,0 +1,93 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestIDs00(t *testing.T) {
+	if uid, err := p.userID(root); err != nil || uid != 0 {
+		t.Error(uid, err)
+	}
+
+	if gid, err := p.groupID(root); err != nil || gid != 0 {
+		t.Error(gid, err)
+	}
+
+	p.newUser("Pam", "Poovey")
+	defer p.deleteGroup("Pam")
+	defer p.deleteUser("Pam")
+
+	uid, _ := p.userID("Pam")
+	gid, _ := p.groupID("Pam")
+	if uid < p.regularIDs.Min || uid > p.regularIDs.Max || gid != uid {
+		t.Error(uid, gid)
+	}
+
+	if name, err := p.userByID(uid); err != nil || name != "Pam" {
+		t.Error(name, err)
+	}
+
+	if _, err := p.userByID(-5); err != errBadID {
+		t.Error(err)
+	}
+
+	if p.newGroupID("sales", uid) != errIDInUse || p.newGroupID("sales", -3) != errBadID {
+		t.Error(nil)
+	}
+
+	p.newGroupID("sales", AutoSystemID)
+	defer p.deleteGroup("sales")
+	if gid, _ := p.groupID("sales"); gid < p.systemIDs.Min || gid > p.systemIDs.Max {
+		t.Error(gid)
+	}
+
+	r, err := p.NewRulesByID(uid, 0, "0640")
+	if err != nil || r.Owner() != "Pam" || r.Group() != root {
+		t.Error(r, err)
+	}
+
+	if u, g, err := p.RulesIDs(r); err != nil || u != uid || g != 0 {
+		t.Error(u, g, err)
+	}
+
+	pam, _ := p.Login("Pam", "Poovey")
+	if id, err := pam.UserID("Pam"); err != nil || id != uid {
+		t.Error(id, err)
+	}
+
+	pam.Logout()
+	if _, err := pam.UserID("Pam"); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := pam.GroupByID(gid); err != errBadSession {
+		t.Error(err)
+	}
+}
+
+func TestIDs01(t *testing.T) {
+	system, regular := p.systemIDs, p.regularIDs
+	defer p.SetIDRanges(system, regular)
+
+	if p.SetIDRanges(IDRange{0, 10}, regular) != errBadIDRange || p.SetIDRanges(IDRange{1, 2000}, regular) != errBadIDRange {
+		t.Error(nil)
+	}
+
+	if p.SetIDRanges(system, IDRange{70000, 70000}) != nil {
+		t.Fatal(nil)
+	}
+
+	p.newGroup("legal")
+	defer p.deleteGroup("legal")
+	if p.newGroup("hr") != errNoFreeID {
+		t.Error(nil)
+	}
+
+	// Rows from before ids existed are given one on setup.
+	p.db.Exec("UPDATE groups SET gid=NULL WHERE name=?", "legal")
+	p.SetIDRanges(system, IDRange{70010, 70020})
+	p.assignIDs()
+	if gid, _ := p.groupID("legal"); gid != 70010 {
+		t.Error(gid)
+	}
+}
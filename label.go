@@ -0,0 +1,139 @@
+package privileges
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Label is a mandatory access control label: a hierarchical level plus a set
+// of compartments. Users hold a label as their clearance and objects as their
+// classification. The zero Label is unclassified.
+type Label struct {
+	Level        int
+	Compartments []string
+}
+
+// ParseLabel parses the form returned by Label.String (2:crypto,nato).
+func ParseLabel(text string) (Label, error) {
+	var l Label
+
+	level := text
+	if i := strings.IndexByte(text, ':'); i >= 0 {
+		level = text[:i]
+		for _, c := range strings.Split(text[i+1:], ",") {
+			if c == "" {
+				return Label{}, errBadLabel
+			}
+			l.Compartments = append(l.Compartments, c)
+		}
+	}
+
+	n, err := strconv.Atoi(level)
+	if err != nil || n < 0 {
+		return Label{}, errBadLabel
+	}
+	l.Level = n
+	sort.Strings(l.Compartments)
+
+	return l, nil
+}
+
+// String returns the level followed by the compartments, if any (2:crypto,nato).
+func (l Label) String() string {
+	s := strconv.Itoa(l.Level)
+	if len(l.Compartments) != 0 {
+		c := append([]string(nil), l.Compartments...)
+		sort.Strings(c)
+		s += ":" + strings.Join(c, ",")
+	}
+	return s
+}
+
+// Dominates reports whether l is at least as high as o: its level is no lower
+// and it includes all of o's compartments.
+func (l Label) Dominates(o Label) bool {
+	if l.Level < o.Level {
+		return false
+	}
+
+	for _, c := range o.Compartments {
+		found := false
+		for _, k := range l.Compartments {
+			if k == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Label returns the classification of the rules.
+func (r *Rules) Label() Label {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.label
+}
+
+// mac applies the Bell-LaPadula rules when mandatory access control is
+// enabled: reading and executing need the session's clearance to dominate the
+// object's classification (no read up), and writing needs the classification
+// to dominate the clearance (no write down).
+func (s *Session) mac(r *Rules, op Op) bool {
+	if !s.p.mac {
+		return true
+	}
+
+	if op&(OpRead|OpExec) != 0 && !s.clearance.Dominates(r.label) {
+		return false
+	}
+
+	if op&OpWrite != 0 && !r.label.Dominates(s.clearance) {
+		return false
+	}
+
+	return true
+}
+
+// Clearance returns the session's clearance.
+func (s *Session) Clearance() Label {
+	return s.clearance
+}
+
+// SetClearance sets a user's clearance. It requires CapMACAdmin and takes
+// effect on the user's next login.
+func (s *Session) SetClearance(username string, l Label) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapMACAdmin) {
+		return errNotCapable
+	}
+
+	return s.p.setClearance(username, l)
+}
+
+// Relabel sets the classification of p. It requires CapMACAdmin.
+func (s *Session) Relabel(p Privileged, l Label) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapMACAdmin) {
+		return errNotCapable
+	}
+
+	r := p.Rules()
+	r.mu.Lock()
+	r.label = l
+	r.mu.Unlock()
+
+	return nil
+}
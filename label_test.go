@@ -0,0 +1,85 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestLabel00(t *testing.T) {
+	for _, text := range []string{"", "x", "-1", "2:", "2:nato,"} {
+		if _, err := ParseLabel(text); err != errBadLabel {
+			t.Error(text)
+		}
+	}
+
+	l, err := ParseLabel("2:nato,crypto")
+	if err != nil || l.Level != 2 || l.String() != "2:crypto,nato" {
+		t.Error(l)
+	}
+
+	low, _ := ParseLabel("1:crypto")
+	if !l.Dominates(low) || low.Dominates(l) || !l.Dominates(Label{}) {
+		t.Error(nil)
+	}
+
+	other, _ := ParseLabel("3:army")
+	if l.Dominates(other) || other.Dominates(l) {
+		t.Error(nil)
+	}
+}
+
+func TestLabel01(t *testing.T) {
+	p.newUser("Lana", "Kane")
+	defer p.deleteGroup("Lana")
+	defer p.deleteUser("Lana")
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	secret := Label{Level: 2, Compartments: []string{"nato"}}
+	if su.SetClearance("Lana", secret) != nil || su.SetClearance("nobody", secret) != errBadName {
+		t.Error(nil)
+	}
+
+	s, _ := p.Login("Lana", "Kane")
+	defer s.Logout()
+
+	if s.Clearance().String() != "2:nato" || s.SetClearance("Lana", Label{Level: 9}) != errNotCapable {
+		t.Error(nil)
+	}
+
+	public, _ := NewRules("Lana", "Lana", "0666")
+	topsecret, _ := NewRules("Lana", "Lana", "0666")
+	su.Relabel(topsecret, Label{Level: 3, Compartments: []string{"nato"}})
+
+	if !s.CanWrite(public) || !s.CanRead(topsecret) {
+		t.Error(nil)
+	}
+
+	p.SetMAC(true)
+	defer p.SetMAC(false)
+
+	if !s.CanRead(public) || s.CanWrite(public) || s.CanRead(topsecret) || !s.CanWrite(topsecret) {
+		t.Error(nil)
+	}
+
+	if _, err := s.Write(public, "leak"); err != errDeniedMAC {
+		t.Error(nil)
+	}
+
+	if _, err := s.Exec(public); err != errDenied {
+		t.Error(nil)
+	}
+
+	d := s.Explain(topsecret, OpRead)
+	want := `user "Lana" requested r--: matched owner granting rw-; denied by mandatory access control`
+	if !d.MAC || d.String() != want {
+		t.Error(d)
+	}
+
+	ok, err := p.CheckAccess("Lana", topsecret, OpRead)
+	if err != nil || ok {
+		t.Error(nil)
+	}
+
+	su.SetClearance("Lana", Label{})
+}
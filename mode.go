@@ -0,0 +1,177 @@
+package privileges
+
+import "strings"
+
+// Mode is a parsed chmod style mode expression. It is either a four digit
+// octal string (0755) or a comma separated list of symbolic clauses such as
+// u+rwx,g-w,o= or a+X.
+type Mode struct {
+	octal   bool
+	bits    uint16
+	clauses []clause
+}
+
+type clause struct {
+	who     uint16 // classes affected, as a mask over the rwx bits
+	actions []action
+}
+
+type action struct {
+	op    byte   // '+', '-' or '='
+	perms string // letters from "rwxXst", or one of "u", "g", "o" to copy
+}
+
+// Class masks over the rwx bits of a rules value.
+const (
+	classUser  = 0x0700
+	classGroup = 0x0070
+	classOther = 0x0007
+	classAll   = classUser | classGroup | classOther
+)
+
+// ParseMode parses a chmod style mode expression. A clause without any of the
+// u, g, o or a letters applies to all classes, as if a had been given.
+func ParseMode(expr string) (*Mode, error) {
+	m := new(Mode)
+
+	if validRules(expr) {
+		r, _ := NewRules("", "", expr)
+		m.octal = true
+		m.bits = r.rules
+		return m, nil
+	}
+
+	if expr == "" {
+		return nil, errBadModeString
+	}
+
+	for _, c := range strings.Split(expr, ",") {
+		cl, err := parseClause(c)
+		if err != nil {
+			return nil, err
+		}
+		m.clauses = append(m.clauses, cl)
+	}
+
+	return m, nil
+}
+
+func parseClause(c string) (clause, error) {
+	var cl clause
+
+	i := 0
+	for ; i < len(c) && strings.IndexByte("ugoa", c[i]) >= 0; i++ {
+		switch c[i] {
+		case 'u':
+			cl.who |= classUser
+		case 'g':
+			cl.who |= classGroup
+		case 'o':
+			cl.who |= classOther
+		case 'a':
+			cl.who |= classAll
+		}
+	}
+
+	if cl.who == 0 {
+		cl.who = classAll
+	}
+
+	if i == len(c) {
+		return cl, errBadModeString
+	}
+
+	for i < len(c) {
+		op := c[i]
+		if op != '+' && op != '-' && op != '=' {
+			return cl, errBadModeString
+		}
+		i++
+
+		j := i
+		if j < len(c) && strings.IndexByte("ugo", c[j]) >= 0 {
+			j++
+		} else {
+			for j < len(c) && strings.IndexByte("rwxXst", c[j]) >= 0 {
+				j++
+			}
+		}
+
+		cl.actions = append(cl.actions, action{op, c[i:j]})
+		i = j
+	}
+
+	return cl, nil
+}
+
+// apply returns the rules value produced by applying the mode to rules.
+func (m *Mode) apply(rules uint16, directory bool) uint16 {
+	if m.octal {
+		return m.bits
+	}
+
+	for _, cl := range m.clauses {
+		for _, a := range cl.actions {
+			bits := a.bits(rules, cl.who, directory)
+			switch a.op {
+			case '+':
+				rules |= bits
+			case '-':
+				rules &^= bits
+			case '=':
+				rules &^= cl.who | specials(cl.who)
+				rules |= bits
+			}
+		}
+	}
+
+	return rules
+}
+
+// bits returns the permission bits named by the action for the classes in
+// who, evaluated against the current rules value.
+func (a action) bits(rules, who uint16, directory bool) uint16 {
+	var perm, special uint16
+
+	for i := 0; i < len(a.perms); i++ {
+		switch a.perms[i] {
+		case 'r':
+			perm |= 4
+		case 'w':
+			perm |= 2
+		case 'x':
+			perm |= 1
+		case 'X':
+			if directory || rules&0x0111 != 0 {
+				perm |= 1
+			}
+		case 's':
+			special |= specials(who) &^ sticky
+		case 't':
+			special |= specials(who) & sticky
+		case 'u':
+			perm |= rules >> 8 & 7
+		case 'g':
+			perm |= rules >> 4 & 7
+		case 'o':
+			perm |= rules & 7
+		}
+	}
+
+	return perm*0x0111&who | special
+}
+
+// specials returns the special bits that belong to the classes in who.
+func specials(who uint16) uint16 {
+	var s uint16
+	if who&classUser != 0 {
+		s |= setuid
+	}
+	if who&classGroup != 0 {
+		s |= setgid
+	}
+	if who&classOther != 0 {
+		s |= sticky
+	}
+	return s
+}
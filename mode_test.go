@@ -0,0 +1,44 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestMode00(t *testing.T) {
+	for _, expr := range []string{"", ",", "u", "z+r", "u+rq", "u+r,", "ug"} {
+		if _, err := ParseMode(expr); err != errBadModeString {
+			t.Error(expr)
+		}
+	}
+}
+
+func TestMode01(t *testing.T) {
+	r, _ := NewRules("alan", "sisatech", "0644")
+
+	cases := []struct {
+		expr, want string
+		dir        bool
+	}{
+		{"0755", "0755", false},
+		{"u+rwx,g-w,o=", "0740", false},
+		{"ug=rw", "0664", false},
+		{"a+X", "0644", false},
+		{"a+X", "0755", true},
+		{"+x", "0755", false},
+		{"go=u", "0666", false},
+		{"u+s,g+s,+t", "7644", false},
+		{"o=rwt", "1646", false},
+		{"a-r+w", "0222", false},
+	}
+
+	for _, c := range cases {
+		mode, err := r.Apply(c.expr, c.dir)
+		if err != nil || mode != c.want {
+			t.Error(c.expr, mode)
+		}
+	}
+
+	if r.Octal() != "0644" {
+		t.Error(nil)
+	}
+}
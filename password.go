@@ -0,0 +1,103 @@
+package privileges
+
+import "time"
+
+// PasswordAging holds the shadow(5) style aging settings of a user's
+// password. Zero durations disable the corresponding check.
+type PasswordAging struct {
+	LastChange time.Time     // when the password was last changed
+	MinAge     time.Duration // how long before the user may change it again
+	MaxAge     time.Duration // how long before it expires
+	Warn       time.Duration // how long before expiry users are warned
+	MustChange bool          // whether it must be changed at the next login
+}
+
+// Expires returns the time the password expires, or the zero Time if it
+// doesn't age.
+func (a *PasswordAging) Expires() time.Time {
+	if a.MaxAge == 0 {
+		return time.Time{}
+	}
+	return a.LastChange.Add(a.MaxAge)
+}
+
+// Expired reports whether the password must be changed before the user may do
+// anything else.
+func (a *PasswordAging) Expired() bool {
+	if a.MustChange {
+		return true
+	}
+	e := a.Expires()
+	return !e.IsZero() && !time.Now().Before(e)
+}
+
+// Warning reports whether the password expires within the warning period.
+func (a *PasswordAging) Warning() bool {
+	e := a.Expires()
+	return !e.IsZero() && a.Warn != 0 && time.Now().Add(a.Warn).After(e)
+}
+
+func (p *Privileges) setPasswordAging(username string, a *PasswordAging) error {
+
+	res, err := p.db.Exec("UPDATE users SET minage=?, maxage=?, warn=?, mustchange=? WHERE name=?",
+		int64(a.MinAge/time.Second), int64(a.MaxAge/time.Second), int64(a.Warn/time.Second), a.MustChange, username)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBadName
+	}
+	return nil
+
+}
+
+// MustChangePassword reports whether the session is restricted because its
+// password has expired or must be changed at login. Until ChangePassword
+// changes it, every other operation that requires a valid session fails with
+// errBadSession; Logout still works.
+func (s *Session) MustChangePassword() bool {
+	return s.restricted
+}
+
+// PasswordAging returns the aging settings of a user's password. Users may
+// read their own; reading anyone else's requires CapUserAdmin.
+func (s *Session) PasswordAging(username string) (*PasswordAging, error) {
+	if !s.live() {
+		return nil, errBadSession
+	}
+
+	if username != "" && username != s.User && !s.Capable(CapUserAdmin) {
+		return nil, errNotCapable
+	}
+
+	if username == "" {
+		username = s.User
+	}
+
+	rec, err := s.p.record(username)
+	if err != nil {
+		return nil, errBadName
+	}
+	return &rec.aging, nil
+}
+
+// SetPasswordAging sets the minimum and maximum age, warning period and
+// must change flag of a user's password. LastChange is ignored; it is updated
+// by ChangePassword. It requires CapUserAdmin, and only a superuser can set
+// the aging of root group members.
+func (s *Session) SetPasswordAging(username string, a *PasswordAging) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
+	return s.p.setPasswordAging(username, a)
+}
@@ -0,0 +1,107 @@
+package privileges
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPassword00(t *testing.T) {
+	p.newUser("Cheryl", "Tunt")
+	defer p.deleteGroup("Cheryl")
+	defer p.deleteUser("Cheryl")
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	if su.SetPasswordAging("Cheryl", &PasswordAging{MinAge: time.Hour, MustChange: true}) != nil {
+		t.Fatal(nil)
+	}
+
+	c, err := p.Login("Cheryl", "Tunt")
+	if err != nil || !c.MustChangePassword() {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	if c.ChangePassword("guest", "", "") != errPasswordExpired || c.SetExpiry("guest", time.Time{}) != errBadSession {
+		t.Error(nil)
+	}
+
+	if _, err := c.Umask("0777"); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := c.Gid("", root); err != errBadSession {
+		t.Error(err)
+	}
+
+	r, _ := NewRules("Cheryl", "Cheryl", "0600")
+	if _, err := c.Read(r); err != errBadSession {
+		t.Error(err)
+	}
+
+	salt, hash := saltAndHash("Carol")
+	if err := c.ChangePassword("", salt, hash); err != nil || c.MustChangePassword() {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Read(r); err != nil {
+		t.Error(err)
+	}
+
+	a, err := c.PasswordAging("")
+	if err != nil || a.MustChange || time.Since(a.LastChange) > time.Minute || a.Expired() {
+		t.Error(a, err)
+	}
+
+	if c.ChangePassword("", salt, hash) != errPasswordTooNew {
+		t.Error(nil)
+	}
+
+	if su.ChangePassword("Cheryl", salt, hash) != nil {
+		t.Error(nil)
+	}
+
+	su.SetPasswordAging("Cheryl", &PasswordAging{MaxAge: time.Hour, Warn: 2 * time.Hour})
+	if a, _ = su.PasswordAging("Cheryl"); a.Expired() || !a.Warning() {
+		t.Error(a)
+	}
+
+	if _, err := c.PasswordAging(root); err != errNotCapable {
+		t.Error(err)
+	}
+}
+
+func TestPassword01(t *testing.T) {
+	p.newUser("Cheryl", "Tunt")
+	defer p.deleteGroup("Cheryl")
+	defer p.deleteUser("Cheryl")
+	p.setPasswordAging("Cheryl", &PasswordAging{MustChange: true})
+
+	c, err := p.Login("Cheryl", "Tunt")
+	if err != nil || !c.MustChangePassword() || !c.live() {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Profile("Cheryl"); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := c.UserCapabilities("Cheryl"); err != errBadSession {
+		t.Error(err)
+	}
+
+	if _, err := c.Expiry("Cheryl"); err != errBadSession {
+		t.Error(err)
+	}
+
+	c.Logout()
+	if _, ok := p.sessions[c.SID]; ok {
+		t.Error(nil)
+	}
+
+	salt, hash := saltAndHash("Carol")
+	if c.ChangePassword("", salt, hash) != errBadSession {
+		t.Error(nil)
+	}
+}
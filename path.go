@@ -0,0 +1,105 @@
+package privileges
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathError records the component of a path or chain that stopped an access
+// check.
+type PathError struct {
+	Path     string    // path of the component, if known
+	Index    int       // position of the component, 0 for the topmost
+	Err      error     // errDenied, or the error looking the component up
+	Decision *Decision // why access was denied, if it was
+}
+
+func (e *PathError) Error() string {
+	name := e.Path
+	if name == "" {
+		name = fmt.Sprintf("component %d", e.Index)
+	}
+
+	if e.Decision != nil {
+		return fmt.Sprintf("%s: %v (%v)", name, e.Err, e.Decision)
+	}
+	return fmt.Sprintf("%s: %v", name, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// searchable presents a path component as a directory to the access checks.
+type searchable struct {
+	Privileged
+}
+
+func (searchable) IsDir() bool {
+	return true
+}
+
+// CheckChain checks that the session can reach the last element of chain, as
+// Unix does when resolving a path: chain lists the ancestors from the topmost
+// down followed by the object itself. Every ancestor needs search (execute)
+// permission and the object needs the bits in op. A denial is reported as a
+// *PathError naming the component.
+func (s *Session) CheckChain(chain []Privileged, op Op) error {
+	for i, p := range chain {
+		want := op
+		if i < len(chain)-1 {
+			p = searchable{p}
+			want = OpExec
+		}
+
+		if d := s.Explain(p, want); !d.Allowed {
+			return &PathError{Index: i, Err: errDenied, Decision: d}
+		}
+	}
+
+	return nil
+}
+
+// CheckPath is like CheckChain for a path in the object store, checking / and
+// every directory below it on the way to name. Components are looked up one at
+// a time, so a missing component is only reported once the session is allowed
+// to search its parent. The object itself is checked as a directory if its
+// rules were stored marked as one.
+func (s *Session) CheckPath(name string, op Op) error {
+	clean, _, err := cleanPath(name)
+	if err != nil {
+		return &PathError{Path: name, Err: err}
+	}
+	name = clean
+
+	paths := []string{"/"}
+	if name != "/" {
+		parts := strings.Split(name[1:], "/")
+		for i := range parts {
+			paths = append(paths, "/"+strings.Join(parts[:i+1], "/"))
+		}
+	}
+
+	for i, path := range paths {
+		r, err := s.p.GetRules(path)
+		if err != nil {
+			return &PathError{Path: path, Index: i, Err: err}
+		}
+
+		var d *Decision
+		switch {
+		case i < len(paths)-1:
+			d = s.Explain(searchable{r}, OpExec)
+		case r.IsDirectory():
+			d = s.Explain(searchable{r}, op)
+		default:
+			d = s.Explain(r, op)
+		}
+
+		if !d.Allowed {
+			return &PathError{Path: path, Index: i, Err: errDenied, Decision: d}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package privileges
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPath00(t *testing.T) {
+	p.newUser("Lana", "Kane")
+	defer p.deleteGroup("Lana")
+	defer p.deleteUser("Lana")
+
+	s, err := p.Login("Lana", "Kane")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	top, _ := NewRules(root, root, "0755")
+	home, _ := NewRules(root, root, "0711")
+	private, _ := NewRules(root, root, "0700")
+	file, _ := NewRules("Lana", "Lana", "0600")
+
+	if s.CheckChain([]Privileged{top, home, file}, OpRead|OpWrite) != nil {
+		t.Error(nil)
+	}
+
+	err = s.CheckChain([]Privileged{top, private, file}, OpRead)
+	e, ok := err.(*PathError)
+	if !ok || e.Index != 1 || !errors.Is(err, errDenied) || e.Decision.Op != OpExec {
+		t.Error(err)
+	}
+
+	top.SetDirectory(true)
+	home.SetDirectory(true)
+	private.SetDirectory(true)
+	p.PutRules("/", top)
+	p.PutRules("/home", home)
+	p.PutRules("/home/lana", file)
+	defer p.DeleteRules("/")
+	defer p.DeleteRules("/home")
+	defer p.DeleteRules("/home/lana")
+
+	if s.CheckPath("/home/lana", OpRead) != nil {
+		t.Error(nil)
+	}
+
+	// A directory at the end of the path can be searched with a capability
+	// despite having no execute bits.
+	p.grantUserCapability("Lana", CapDACReadSearch)
+	reader, _ := p.Login("Lana", "Kane")
+	p.PutRules("/home", private)
+	if reader.CheckPath("/home", OpExec) != nil {
+		t.Error(nil)
+	}
+	if reader.CheckPath("/home/lana", OpExec) == nil {
+		t.Error(nil)
+	}
+	reader.Logout()
+	p.revokeUserCapability("Lana", CapDACReadSearch)
+	p.PutRules("/home", home)
+
+	err = s.CheckPath("/home/lana/notes", OpRead)
+	if e, ok := err.(*PathError); !ok || e.Path != "/home/lana" || !errors.Is(err, errDenied) {
+		t.Error(err)
+	}
+
+	err = s.CheckPath("/home/ray", OpRead)
+	if e, ok := err.(*PathError); !ok || e.Path != "/home/ray" || !errors.Is(err, errNoObject) {
+		t.Error(err)
+	}
+
+	p.PutRules("/home", private)
+	err = s.CheckPath("/home/lana", OpRead)
+	want := `/home: access denied (user "Lana" requested --x: matched other granting ---; denied)`
+	if err == nil || err.Error() != want {
+		t.Error(err)
+	}
+}
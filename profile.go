@@ -0,0 +1,145 @@
+package privileges
+
+// Profile fields stored for every user. Any other field name is a custom
+// attribute.
+const (
+	FieldFullName = "fullname"
+	FieldHome     = "home"
+	FieldShell    = "shell"
+	FieldEmail    = "email"
+)
+
+// Profile holds the descriptive details of a user.
+type Profile struct {
+	FullName string
+	Home     string
+	Shell    string
+	Email    string
+	Attrs    map[string]string // custom attributes
+}
+
+func (p *Privileges) createUserAttrsTable() {
+
+	p.db.Exec("CREATE TABLE IF NOT EXISTS userattrs (" +
+		"username VARCHAR(64) NOT NULL, " +
+		"key VARCHAR(64) NOT NULL, " +
+		"value TEXT NOT NULL, " +
+		"PRIMARY KEY (username, key), " +
+		"FOREIGN KEY (username) REFERENCES users(name) ON DELETE CASCADE" +
+		");")
+	p.db.Exec("CREATE INDEX IF NOT EXISTS userattrskey ON userattrs(key, value);")
+
+}
+
+// builtinField reports whether field is one of the fields stored in the users
+// table, which is also its column name.
+func builtinField(field string) bool {
+	switch field {
+	case FieldFullName, FieldHome, FieldShell, FieldEmail:
+		return true
+	}
+	return false
+}
+
+func (p *Privileges) profile(username string) (*Profile, error) {
+
+	pr := &Profile{Attrs: make(map[string]string)}
+	row := p.db.QueryRow("SELECT fullname, home, shell, email FROM users WHERE name=?", username)
+	err := row.Scan(&pr.FullName, &pr.Home, &pr.Shell, &pr.Email)
+	if err != nil {
+		return nil, errBadName
+	}
+
+	rows, err := p.db.Query("SELECT key, value FROM userattrs WHERE username=?", username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var key, value string
+	for rows.Next() {
+		rows.Scan(&key, &value)
+		pr.Attrs[key] = value
+	}
+
+	return pr, nil
+
+}
+
+// setProfileField sets a field of the user's profile. Setting a custom
+// attribute to "" removes it.
+func (p *Privileges) setProfileField(username, field, value string) error {
+
+	if field == "" {
+		return errBadField
+	}
+
+	if _, err := p.record(username); err != nil {
+		return errBadName
+	}
+
+	var err error
+	switch {
+	case builtinField(field):
+		_, err = p.db.Exec("UPDATE users SET "+field+"=? WHERE name=?", value, username)
+	case value == "":
+		_, err = p.db.Exec("DELETE FROM userattrs WHERE username=? AND key=?", username, field)
+	default:
+		_, err = p.db.Exec("INSERT OR REPLACE INTO userattrs(username, key, value) VALUES(?, ?, ?)", username, field, value)
+	}
+	return err
+
+}
+
+func (p *Privileges) usersByField(field, value string) ([]string, error) {
+
+	if field == "" {
+		return nil, errBadField
+	}
+
+	if builtinField(field) {
+		return p.listNames("SELECT name FROM users WHERE "+field+"=? ORDER BY name", value)
+	}
+	return p.listNames("SELECT username FROM userattrs WHERE key=? AND value=? ORDER BY username", field, value)
+
+}
+
+// Profile returns a user's profile.
+func (s *Session) Profile(username string) (*Profile, error) {
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	return s.p.profile(username)
+}
+
+// SetProfileField sets a field of a user's profile, either one of the Field
+// constants or a custom attribute; setting a custom attribute to "" removes
+// it. Users may set their own full name and email, everything else requires
+// CapUserAdmin.
+func (s *Session) SetProfileField(username, field, value string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	self := username == "" || username == s.User
+	if self {
+		username = s.User
+	}
+
+	if !(self && (field == FieldFullName || field == FieldEmail)) && !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	return s.p.setProfileField(username, field, value)
+}
+
+// UsersByField returns the users whose profile field, either one of the Field
+// constants or a custom attribute, is set to value.
+func (s *Session) UsersByField(field, value string) ([]string, error) {
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	return s.p.usersByField(field, value)
+}
@@ -0,0 +1,51 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestProfile00(t *testing.T) {
+	p.newUser("Ray", "Gillette")
+	defer p.deleteGroup("Ray")
+	defer p.deleteUser("Ray")
+
+	ray, _ := p.Login("Ray", "Gillette")
+	defer ray.Logout()
+
+	if ray.SetProfileField("", FieldFullName, "Ray Gillette") != nil || ray.SetProfileField("Ray", FieldEmail, "ray@isis.com") != nil {
+		t.Error(nil)
+	}
+
+	if ray.SetProfileField("Ray", FieldShell, "/bin/zsh") != errNotCapable || ray.SetProfileField("guest", FieldFullName, "Guest") != errNotCapable {
+		t.Error(nil)
+	}
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	if su.SetProfileField("Ray", FieldShell, "/bin/zsh") != nil || su.SetProfileField("Ray", "team", "field") != nil {
+		t.Error(nil)
+	}
+
+	if su.SetProfileField("nobody", "team", "field") != errBadName || su.SetProfileField("Ray", "", "x") != errBadField {
+		t.Error(nil)
+	}
+
+	pr, err := ray.Profile("Ray")
+	if err != nil || pr.FullName != "Ray Gillette" || pr.Email != "ray@isis.com" || pr.Shell != "/bin/zsh" || pr.Attrs["team"] != "field" {
+		t.Error(pr, err)
+	}
+
+	if users, _ := ray.UsersByField("team", "field"); len(users) != 1 || users[0] != "Ray" {
+		t.Error(users)
+	}
+
+	if users, _ := ray.UsersByField(FieldEmail, "ray@isis.com"); len(users) != 1 || users[0] != "Ray" {
+		t.Error(users)
+	}
+
+	su.SetProfileField("Ray", "team", "")
+	if pr, _ = ray.Profile("Ray"); len(pr.Attrs) != 0 {
+		t.Error(pr.Attrs)
+	}
+}
@@ -0,0 +1,188 @@
+package privileges
+
+import "database/sql"
+
+// renameUser renames a user along with its personal group, if it has one, in
+// a single transaction.
+func (p *Privileges) renameUser(oldname, newname string) error {
+
+	if oldname == root || newname == root {
+		return errRoot
+	}
+
+	if newname == "" {
+		return errBadName
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The rows referencing the user are only consistent again once all of
+	// them have been updated.
+	if _, err = tx.Exec("PRAGMA defer_foreign_keys=ON"); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec("UPDATE users SET name=? WHERE name=?", newname, oldname)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBadName
+	}
+
+	for _, query := range []string{
+		"UPDATE usersgroups SET username=? WHERE username=?",
+		"UPDATE userscaps SET username=? WHERE username=?",
+		"UPDATE userattrs SET username=? WHERE username=?",
+		"UPDATE objects SET owner=? WHERE owner=?",
+	} {
+		if _, err = tx.Exec(query, newname, oldname); err != nil {
+			return err
+		}
+	}
+
+	var x string
+	err = tx.QueryRow("SELECT name FROM groups WHERE name=?", oldname).Scan(&x)
+	if err == nil {
+		err = renameGroup(tx, oldname, newname)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, s := range p.sessions {
+		if s.User == oldname {
+			s.User = newname
+		}
+	}
+	p.renamedGroup(oldname, newname)
+
+	return nil
+
+}
+
+// renameGroup renames a group in a single transaction.
+func (p *Privileges) renameGroup(oldname, newname string) error {
+
+	if oldname == root || newname == root {
+		return errRoot
+	}
+
+	if newname == "" {
+		return errBadName
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("PRAGMA defer_foreign_keys=ON"); err != nil {
+		return err
+	}
+
+	if err = renameGroup(tx, oldname, newname); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	p.renamedGroup(oldname, newname)
+
+	return nil
+
+}
+
+// renameGroup updates a group's name and every row referencing it.
+func renameGroup(tx *sql.Tx, oldname, newname string) error {
+
+	res, err := tx.Exec("UPDATE groups SET name=? WHERE name=?", newname, oldname)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBadName
+	}
+
+	for _, query := range []string{
+		"UPDATE usersgroups SET groupname=? WHERE groupname=?",
+		"UPDATE users SET gid=? WHERE gid=?",
+		"UPDATE groupscaps SET groupname=? WHERE groupname=?",
+		"UPDATE objects SET grp=? WHERE grp=?",
+	} {
+		if _, err = tx.Exec(query, newname, oldname); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// renamedGroup updates the live sessions after a group is renamed.
+func (p *Privileges) renamedGroup(oldname, newname string) {
+
+	for _, s := range p.sessions {
+		if s.gid == oldname {
+			s.gid = newname
+		}
+		for i, g := range s.groups {
+			if g == oldname {
+				s.groups[i] = newname
+			}
+		}
+	}
+
+}
+
+// RenameUser renames a user, and its personal group if it has one. Group
+// memberships, capabilities, the ownership of stored objects and live sessions
+// follow the new name; Rules held in memory keep the old one. It requires
+// CapUserAdmin and every capability the user holds; only superusers may rename
+// members of the root group, and root can't be renamed.
+func (s *Session) RenameUser(oldname, newname string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversUser(oldname); err != nil {
+		return err
+	}
+
+	return s.p.renameUser(oldname, newname)
+}
+
+// RenameGroup renames a group. Memberships, primary groups, capabilities, the
+// group of stored objects and live sessions follow the new name; Rules held
+// in memory keep the old one. It requires CapGroupAdmin and every capability
+// granted to the group, and root can't be renamed.
+func (s *Session) RenameGroup(oldname, newname string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapGroupAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversGroup(oldname); err != nil {
+		return err
+	}
+
+	return s.p.renameGroup(oldname, newname)
+}
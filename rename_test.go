@@ -0,0 +1,103 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestRename00(t *testing.T) {
+	p.newUser("Barry", "Dylan")
+	p.newGroup("kgb")
+	p.addToGroup("Barry", "kgb")
+	p.grantGroupCapability("kgb", CapChown)
+	defer p.deleteGroup("kgb")
+
+	barry, _ := p.Login("Barry", "Dylan")
+	defer barry.Logout()
+
+	r, _ := NewRules("Barry", "kgb", "0640")
+	p.PutRules("/", r)
+	defer p.DeleteRules("/")
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	if su.RenameUser(root, "admin") != errRoot || su.RenameGroup("kgb", root) != errRoot {
+		t.Error(nil)
+	}
+
+	if su.RenameUser("nobody", "somebody") != errBadName {
+		t.Error(nil)
+	}
+
+	if err := su.RenameUser("Barry", "Bane"); err != nil {
+		t.Fatal(err)
+	}
+	defer p.deleteGroup("Bane")
+	defer p.deleteUser("Bane")
+
+	if err := su.RenameGroup("kgb", "cia"); err != nil {
+		t.Fatal(err)
+	}
+	defer p.deleteGroup("cia")
+
+	if _, err := p.record("Barry"); err == nil {
+		t.Error(nil)
+	}
+
+	if gid, _ := p.gid("Bane"); gid != "Bane" {
+		t.Error(gid)
+	}
+
+	if in, _ := p.inGroup("Bane", "cia"); !in {
+		t.Error(nil)
+	}
+
+	if caps, _ := p.userCapabilities("Bane"); len(caps) != 1 || caps[0] != CapChown {
+		t.Error(caps)
+	}
+
+	if q, err := p.GetRules("/"); err != nil || q.Owner() != "Bane" || q.Group() != "cia" {
+		t.Error(err)
+	}
+
+	if barry.User != "Bane" || barry.gid != "Bane" || !barry.member("cia") {
+		t.Error(barry.User, barry.groups)
+	}
+
+	// A clash rolls back the whole rename.
+	if su.RenameUser("Bane", "guest") == nil {
+		t.Error(nil)
+	}
+
+	if _, err := p.record("Bane"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRename01(t *testing.T) {
+	p.newUser("Katya", "Kazanova")
+	p.grantUserCapability("Katya", CapUserAdmin)
+	p.grantUserCapability("Katya", CapGroupAdmin)
+	defer p.deleteGroup("Katya")
+	defer p.deleteUser("Katya")
+
+	p.newUser("Boss", "Boss")
+	p.addToGroup("Boss", root)
+	defer p.deleteGroup("Boss")
+	defer p.deleteUser("Boss")
+
+	p.newGroup("kgb")
+	p.grantGroupCapability("kgb", CapMACAdmin)
+	defer p.deleteGroup("kgb")
+
+	s, _ := p.Login("Katya", "Kazanova")
+	defer s.Logout()
+
+	if s.RenameUser("Boss", "Former") != errNotSU || s.RenameGroup("kgb", "fsb") != errNotCapable {
+		t.Error(nil)
+	}
+
+	if _, err := p.record("Boss"); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,15 +1,43 @@
 package privileges
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Rules are safe for concurrent use: methods reading them hold mu for reading
+// and methods changing them hold it for writing. Unexported helpers expect the
+// caller to hold it.
 type Rules struct {
+	mu    sync.RWMutex
 	owner string
 	group string
 	rules uint16
+	acl   *acl
+	def   ACL
+	attrs Attr
+	verbs map[string]uint16
+	label Label
+	dir   bool
 }
 
+// Special permission bits, stored in the nibble above the owner's bits.
+const (
+	setuid = 4 << 12
+	setgid = 2 << 12
+	sticky = 1 << 12
+)
+
+// Rules returns r, making *Rules a Privileged on its own so its permissions
+// can be checked and changed without an object attached.
 func (r *Rules) Rules() *Rules {
 	return r
 }
 
+// Read, Write and Exec do nothing and return nil. To protect a value use
+// Guarded, or implement Privileged on the resource type; embedding *Rules
+// doesn't work as its field name clashes with the Rules method.
 func (r *Rules) Read(args ...string) interface{} {
 	return nil
 }
@@ -28,7 +56,7 @@ func NewRules(owner, group, rules string) (*Rules, error) {
 	}
 
 	var a uint16
-	for i := 1; i < 4; i++ {
+	for i := 0; i < 4; i++ {
 		a = a<<4 | uint16(rules[i]-'0')
 	}
 	r := new(Rules)
@@ -39,10 +67,10 @@ func NewRules(owner, group, rules string) (*Rules, error) {
 }
 
 func validRules(rules string) bool {
-	if len(rules) != 4 || rules[0] != '0' {
+	if len(rules) != 4 {
 		return false
 	}
-	for i := 1; i < 4; i++ {
+	for i := 0; i < 4; i++ {
 		if rules[i] < '0' || rules[i] > '7' {
 			return false
 		}
@@ -50,26 +78,109 @@ func validRules(rules string) bool {
 	return true
 }
 
-// Rules returns the octal representation of the file's permissions (0777)
+// Octal returns the octal representation of the file's permissions (4755),
+// including the setuid, setgid and sticky bits in the leading digit.
 func (r *Rules) Octal() string {
-	a := []byte("0")
-	for i := 2; i >= 0; i-- {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.octal()
+}
+
+func (r *Rules) octal() string {
+	var a []byte
+	for i := 3; i >= 0; i-- {
 		a = append(a, byte(r.rules>>(4*uint(i))&7+'0'))
 	}
 	return string(a)
 }
 
+// Apply returns the octal representation of the permissions that result from
+// applying the chmod style mode expression to the rules (u+rwx,g-w,o=). The
+// rules themselves are left unchanged. The directory flag controls how X is
+// interpreted.
+func (r *Rules) Apply(mode string, directory bool) (string, error) {
+	m, err := ParseMode(mode)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.RLock()
+	a := &Rules{rules: m.apply(r.rules, directory)}
+	r.mu.RUnlock()
+
+	return a.octal(), nil
+}
+
 // Owner returns a string naming the user owner identified by the permissions
 func (r *Rules) Owner() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	return r.owner
 }
 
 // Group returns a string naming the group identified by the permissions
 func (r *Rules) Group() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	return r.group
 }
 
+// Setuid reports whether the set-user-ID bit is set.
+func (r *Rules) Setuid() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rules&setuid != 0
+}
+
+// Setgid reports whether the set-group-ID bit is set. On a directory it makes
+// new children inherit the directory's group.
+func (r *Rules) Setgid() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rules&setgid != 0
+}
+
+// Sticky reports whether the sticky bit is set. On a directory it restricts
+// deletion of entries to their owner, the directory's owner and superusers.
+func (r *Rules) Sticky() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rules&sticky != 0
+}
+
+// IsDirectory reports whether r is marked as the rules of a directory. The
+// object store keeps the mark, and CheckPath checks marked paths as
+// directories.
+func (r *Rules) IsDirectory() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.dir
+}
+
+// SetDirectory marks r as the rules of a directory, or of a file if dir is
+// false.
+func (r *Rules) SetDirectory(dir bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dir = dir
+}
+
+// Symbolic returns the ls style representation of the permissions followed by
+// the owner and group (drwsr-s--T owner group). As with ls, a + follows the
+// permissions of rules with an extended ACL; the ACL itself isn't included,
+// ACLText returns it.
 func (r *Rules) Symbolic(directory bool) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	sym := []byte("----------")
 	if directory {
 		sym[0] = 'd'
@@ -111,6 +222,22 @@ func (r *Rules) Symbolic(directory bool) string {
 		sym[9] = 'x'
 	}
 
+	if r.rules&setuid != 0 {
+		sym[3] = special(sym[3], 's')
+	}
+
+	if r.rules&setgid != 0 {
+		sym[6] = special(sym[6], 's')
+	}
+
+	if r.rules&sticky != 0 {
+		sym[9] = special(sym[9], 't')
+	}
+
+	if r.acl != nil {
+		sym = append(sym, '+')
+	}
+
 	sym = append(sym, ' ')
 	sym = append(sym, []byte(r.owner)...)
 
@@ -119,3 +246,84 @@ func (r *Rules) Symbolic(directory bool) string {
 
 	return string(sym)
 }
+
+// ParseSymbolic parses the representation produced by Symbolic back into
+// rules, reporting whether it described a directory. If the permissions are
+// followed by a +, the rules had an extended ACL the text doesn't carry: the
+// parsed rules are returned together with an error wrapping errACLMissing, and
+// the ACL can be restored with SetACLText.
+func ParseSymbolic(symbolic string) (*Rules, bool, error) {
+	fields := strings.SplitN(symbolic, " ", 3)
+	if len(fields) != 3 {
+		return nil, false, fmt.Errorf("%w %q: expected mode, owner and group", errBadSymbolic, symbolic)
+	}
+
+	sym := fields[0]
+	extended := strings.HasSuffix(sym, "+")
+	if extended {
+		sym = sym[:len(sym)-1]
+	}
+
+	if len(sym) != 10 {
+		return nil, false, fmt.Errorf("%w %q: mode must be 10 characters long", errBadSymbolic, symbolic)
+	}
+
+	var directory bool
+	switch sym[0] {
+	case 'd':
+		directory = true
+	case '-':
+	default:
+		return nil, false, fmt.Errorf("%w %q: unknown file type %q", errBadSymbolic, symbolic, sym[0])
+	}
+
+	r := new(Rules)
+	r.owner = fields[1]
+	r.group = fields[2]
+
+	marks := []struct {
+		bit   uint16
+		upper byte
+	}{{setuid, 'S'}, {setgid, 'S'}, {sticky, 'T'}}
+
+	for i := 0; i < 3; i++ {
+		shift := uint(8 - 4*i)
+		for j, c := range []byte("rwx") {
+			k := 1 + 3*i + j
+			switch sym[k] {
+			case '-':
+				continue
+			case c:
+				r.rules |= 4 >> uint(j) << shift
+				continue
+			}
+
+			if j != 2 {
+				return nil, false, fmt.Errorf("%w %q: unexpected %q at position %d", errBadSymbolic, symbolic, sym[k], k)
+			}
+
+			switch sym[k] {
+			case marks[i].upper:
+				r.rules |= marks[i].bit
+			case marks[i].upper - 'A' + 'a':
+				r.rules |= marks[i].bit | 1<<shift
+			default:
+				return nil, false, fmt.Errorf("%w %q: unexpected %q at position %d", errBadSymbolic, symbolic, sym[k], k)
+			}
+		}
+	}
+
+	if extended {
+		return r, directory, fmt.Errorf("%w from %q", errACLMissing, symbolic)
+	}
+	return r, directory, nil
+}
+
+// special returns the symbol for a special bit shown in an execute position:
+// lower case when the execute bit is also set, upper case otherwise.
+func special(x, c byte) byte {
+	if x == 'x' {
+		return c
+	}
+	return c - 'a' + 'A'
+}
@@ -1,6 +1,7 @@
 package privileges
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -12,7 +13,7 @@ func TestRules00(t *testing.T) {
 }
 
 func TestRules01(t *testing.T) {
-	_, err := NewRules("alan", "sisatech", "1111")
+	_, err := NewRules("alan", "sisatech", "8111")
 	if err != errBadRulesString {
 		t.Error(nil)
 	}
@@ -45,7 +46,7 @@ func TestRules05(t *testing.T) {
 		t.Error(nil)
 	}
 
-	if r.Rules() != "0740" {
+	if r.Octal() != "0740" || r.Rules() != r {
 		t.Error(nil)
 	}
 
@@ -57,3 +58,81 @@ func TestRules05(t *testing.T) {
 		t.Error(nil)
 	}
 }
+
+func TestRules06(t *testing.T) {
+	r, err := NewRules("alan", "sisatech", "4755")
+	if err != nil {
+		t.Error(nil)
+	}
+
+	if r.Octal() != "4755" || !r.Setuid() || r.Setgid() || r.Sticky() {
+		t.Error(nil)
+	}
+
+	if r.Symbolic(false) != "-rwsr-xr-x alan sisatech" {
+		t.Error(nil)
+	}
+}
+
+func TestRules07(t *testing.T) {
+	r, _ := NewRules("alan", "sisatech", "3764")
+	if r.Octal() != "3764" || !r.Setgid() || !r.Sticky() {
+		t.Error(nil)
+	}
+
+	if r.Symbolic(true) != "drwxrwSr-T alan sisatech" {
+		t.Error(nil)
+	}
+}
+
+func TestRules08(t *testing.T) {
+	for _, mode := range []string{"0000", "0740", "4755", "2775", "1777", "7000", "7777"} {
+		for _, dir := range []bool{false, true} {
+			r, _ := NewRules("alan", "sisatech", mode)
+			sym := r.Symbolic(dir)
+
+			q, d, err := ParseSymbolic(sym)
+			if err != nil || d != dir || q.Octal() != mode || q.Symbolic(dir) != sym {
+				t.Error(sym)
+			}
+		}
+	}
+}
+
+func TestRules09(t *testing.T) {
+	bad := []string{
+		"",
+		"-rwxr-xr-x alan",
+		"-rwxr-xr- alan sisatech",
+		"lrwxr-xr-x alan sisatech",
+		"-rwxr-xr-s alan sisatech",
+		"-rwtr-xr-x alan sisatech",
+		"-wrxr-xr-x alan sisatech",
+	}
+
+	for _, sym := range bad {
+		if _, _, err := ParseSymbolic(sym); !errors.Is(err, errBadSymbolic) {
+			t.Error(sym)
+		}
+	}
+}
+
+func TestRules10(t *testing.T) {
+	r, _ := NewRules("alan", "sisatech", "0750")
+	a, _ := ParseACL("user::rwx,user:bob:r--,group::r-x,mask::r-x,other::---")
+	r.SetACL(a)
+
+	sym := r.Symbolic(true)
+	if sym != "drwxr-x---+ alan sisatech" {
+		t.Error(sym)
+	}
+
+	q, d, err := ParseSymbolic(sym)
+	if !errors.Is(err, errACLMissing) || q == nil || !d || q.Octal() != "0750" {
+		t.Fatal(err)
+	}
+
+	if q.SetACLText(r.ACLText()) != nil || q.Symbolic(true) != sym {
+		t.Error(q.Symbolic(true))
+	}
+}
@@ -1,5 +1,10 @@
 package privileges
 
+import (
+	"context"
+	"time"
+)
+
 type Privileged interface {
 	Rules() *Rules
 	Read(...string) interface{}
@@ -7,15 +12,31 @@ type Privileged interface {
 	Exec(...string) interface{}
 }
 
+// Directory is implemented by Privileged objects that may act as directories.
+// The session consults it wherever POSIX treats directories differently.
+type Directory interface {
+	Privileged
+	IsDir() bool
+}
+
+func isDir(p Privileged) bool {
+	d, ok := p.(Directory)
+	return ok && d.IsDir()
+}
+
 type Session struct {
-	p      *Privileges
-	SID    string
-	User   string
-	Hash   string
-	su     bool
-	gid    string
-	groups []string
-	umask  string
+	p          *Privileges
+	SID        string
+	User       string
+	Hash       string
+	su         bool
+	caps       map[Capability]bool
+	clearance  Label
+	gid        string
+	groups     []string
+	umask      string
+	expires    int64
+	restricted bool
 }
 
 func (s *Session) Logout() {
@@ -29,8 +50,8 @@ func (s *Session) NewUser(username, salt, hashword string) error {
 		return errBadSession
 	}
 
-	if !s.su {
-		return errNotSU
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
 	}
 
 	err := s.p.newUserHash(username, salt, hashword)
@@ -38,36 +59,84 @@ func (s *Session) NewUser(username, salt, hashword string) error {
 
 }
 
-func (s *Session) ChangePassword(username, salt, hashword string) error {
+// NewUserID is like NewUser but takes the uid to give the user and its
+// personal group, or AutoID or AutoSystemID to allocate one.
+func (s *Session) NewUserID(username, salt, hashword string, id int) error {
 	if !s.valid() {
 		return errBadSession
 	}
 
-	if username == "" || username == s.User {
-		s.p.changePassword(s.User, salt, hashword)
-	} else if s.su {
-		s.p.changePassword(username, salt, hashword)
-	} else {
-		return errDenied
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
+	}
+
+	return s.p.newUserHashID(username, salt, hashword, id)
+
+}
+
+// ChangePassword changes a user's password. Changing another user's password
+// requires CapUserAdmin and every capability that user holds; only superusers
+// may change the password of root or of members of the root group. Users
+// without CapUserAdmin can't change their own password again before its
+// minimum age has passed, unless it has expired. This is the only operation a
+// session restricted by an expired password may perform, and changing its own
+// password lifts the restriction.
+func (s *Session) ChangePassword(username, salt, hashword string) error {
+	if !s.live() {
+		return errBadSession
 	}
 
+	if username != "" && username != s.User {
+		if s.restricted {
+			return errPasswordExpired
+		}
+		if !s.Capable(CapUserAdmin) {
+			return errNotCapable
+		}
+		if err := s.coversUser(username); err != nil {
+			return err
+		}
+		return s.p.changePassword(username, salt, hashword)
+	}
+
+	if !s.restricted && !s.Capable(CapUserAdmin) {
+		rec, err := s.p.record(s.User)
+		if err != nil {
+			return err
+		}
+		if !rec.aging.Expired() && time.Now().Before(rec.aging.LastChange.Add(rec.aging.MinAge)) {
+			return errPasswordTooNew
+		}
+	}
+
+	if err := s.p.changePassword(s.User, salt, hashword); err != nil {
+		return err
+	}
+
+	s.restricted = false
 	return nil
 
 }
 
+// DeleteUser deletes a user. It requires CapUserAdmin and every capability the
+// user holds; only superusers may delete members of the root group.
 func (s *Session) DeleteUser(username string) error {
 	if !s.valid() {
 		return errBadSession
 	}
 
-	if !s.su {
-		return errNotSU
+	if !s.Capable(CapUserAdmin) {
+		return errNotCapable
 	}
 
 	if username == s.User {
 		return errDenied
 	}
 
+	if err := s.coversUser(username); err != nil {
+		return err
+	}
+
 	s.p.deleteUser(username)
 
 	return s.p.deleteUser(username)
@@ -79,42 +148,78 @@ func (s *Session) NewGroup(name string) error {
 		return errBadSession
 	}
 
-	if !s.su {
-		return errNotSU
+	if !s.Capable(CapGroupAdmin) {
+		return errNotCapable
 	}
 
 	return s.p.newGroup(name)
 }
 
+// NewGroupID is like NewGroup but takes the gid to give the group, or AutoID
+// or AutoSystemID to allocate one.
+func (s *Session) NewGroupID(name string, id int) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.Capable(CapGroupAdmin) {
+		return errNotCapable
+	}
+
+	return s.p.newGroupID(name, id)
+}
+
 func (s *Session) DeleteGroup(name string) error {
 	if !s.valid() {
 		return errBadSession
 	}
 
-	if !s.su {
-		return errNotSU
+	if !s.Capable(CapGroupAdmin) {
+		return errNotCapable
 	}
 
 	return s.p.deleteGroup(name)
 }
 
+// Gid returns a user's primary group, or sets it if group isn't empty. Users
+// may only switch to groups they are members of; setting another group, or
+// another user's primary group, requires CapUserAdmin.
 func (s *Session) Gid(username, group string) (string, error) {
+	if !s.valid() {
+		return "", errBadSession
+	}
 
 	if username == s.User || username == "" {
 		if group == "" {
 			return s.gid, nil
 		}
-		return "", s.p.setGid(s.User, group)
+		if !s.member(group) && !s.Capable(CapUserAdmin) {
+			return "", errDenied
+		}
+		err := s.p.setGid(s.User, group)
+		if err == nil {
+			s.gid = group
+		}
+		return "", err
 	}
 
 	if group == "" {
 		return s.p.gid(username)
 	}
+
+	if !s.Capable(CapUserAdmin) {
+		return "", errNotCapable
+	}
 	return "", s.p.setGid(username, group)
 
 }
 
+// Umask returns the session's umask, or sets the user's if mask isn't empty.
 func (s *Session) Umask(mask string) (string, error) {
+	if !s.valid() {
+		return "", errBadSession
+	}
+
 	if mask == "" {
 		return s.umask, nil
 	}
@@ -130,13 +235,19 @@ func (s *Session) Umask(mask string) (string, error) {
 
 }
 
+// UserAddGroup adds a user to a group. It requires CapGroupAdmin and every
+// capability granted to the group; only superusers may add users to root.
 func (s *Session) UserAddGroup(username, group string) error {
 	if !s.valid() {
 		return errBadSession
 	}
 
-	if !s.su {
-		return errNotSU
+	if !s.Capable(CapGroupAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversGroup(group); err != nil {
+		return err
 	}
 
 	return s.p.addToGroup(username, group)
@@ -147,13 +258,20 @@ func (s *Session) UserInGroup(username, group string) (bool, error) {
 	return s.p.inGroup(username, group)
 }
 
+// UserRemoveGroup removes a user from a group. It requires CapGroupAdmin and
+// every capability granted to the group; only superusers may remove users
+// from root.
 func (s *Session) UserRemoveGroup(username, group string) error {
 	if !s.valid() {
 		return errBadSession
 	}
 
-	if !s.su {
-		return errNotSU
+	if !s.Capable(CapGroupAdmin) {
+		return errNotCapable
+	}
+
+	if err := s.coversGroup(group); err != nil {
+		return err
 	}
 
 	return s.p.removeFromGroup(username, group)
@@ -176,82 +294,175 @@ func (s *Session) GroupListUsersGids(group string) ([]string, error) {
 	return s.p.listUsersWithGid(group)
 }
 
-func (s *Session) valid() bool {
+// Capable reports whether the session holds the capability c, either granted
+// to its user or one of its groups, or as a superuser.
+func (s *Session) Capable(c Capability) bool {
+	return s.su || s.caps[c]
+}
 
-	_, ok := s.p.sessions[s.SID]
-	return ok
+// coversUser returns nil if the session holds every privilege of username, so
+// that taking over the account gives it nothing new. Only superusers cover
+// root and the members of the root group.
+func (s *Session) coversUser(username string) error {
+	if s.su {
+		return nil
+	}
 
+	if username == root {
+		return errNotSU
+	}
+	if in, _ := s.p.inGroup(username, root); in {
+		return errNotSU
+	}
+
+	caps, err := s.p.userCapabilities(username)
+	if err != nil {
+		return err
+	}
+	return s.holds(caps)
 }
 
-func (s *Session) CanRead(p Privileged) bool {
-	r := p.Rules()
-	if s.User == r.Owner() {
-		return r.rules>>8&4 == 4
+// coversGroup returns nil if the session holds every privilege membership of
+// group grants. Only superusers cover the root group.
+func (s *Session) coversGroup(group string) error {
+	if s.su {
+		return nil
 	}
 
-	for _, g := range s.groups {
-		if g == r.Group() {
-			return r.rules>>4&4 == 4
-		}
+	if group == root {
+		return errNotSU
 	}
 
-	return r.rules&4 == 4
+	caps, err := s.p.groupCapabilities(group)
+	if err != nil {
+		return err
+	}
+	return s.holds(caps)
+}
 
+func (s *Session) holds(caps []Capability) error {
+	for _, c := range caps {
+		if !s.Capable(c) {
+			return errNotCapable
+		}
+	}
+	return nil
 }
 
-func (s *Session) Read(p Privileged, args ...string) (interface{}, error) {
-	if s.CanRead(p) {
-		return p.Read(args...), nil
+// GrantUserCapability grants the capability c to a user. Only superusers may
+// grant capabilities. It takes effect on the user's next login.
+func (s *Session) GrantUserCapability(username string, c Capability) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.su {
+		return errNotSU
 	}
-	return nil, errDenied
 
+	return s.p.grantUserCapability(username, c)
 }
 
-func (s *Session) CanWrite(p Privileged) bool {
-	r := p.Rules()
-	if s.User == r.Owner() {
-		return r.rules>>8&2 == 2
+// RevokeUserCapability revokes a capability granted directly to a user.
+func (s *Session) RevokeUserCapability(username string, c Capability) error {
+	if !s.valid() {
+		return errBadSession
 	}
 
-	for _, g := range s.groups {
-		if g == r.Group() {
-			return r.rules>>4&2 == 2
-		}
+	if !s.su {
+		return errNotSU
 	}
 
-	return r.rules&2 == 2
+	return s.p.revokeUserCapability(username, c)
 }
 
-func (s *Session) Write(p Privileged, args ...string) (interface{}, error) {
-	if s.CanWrite(p) {
-		return p.Write(args...), nil
+// GrantGroupCapability grants the capability c to every member of a group.
+func (s *Session) GrantGroupCapability(group string, c Capability) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	if !s.su {
+		return errNotSU
 	}
-	return nil, errDenied
+
+	return s.p.grantGroupCapability(group, c)
 }
 
-func (s Session) CanExec(p Privileged) bool {
-	r := p.Rules()
-	if s.User == r.Owner() {
-		return r.rules>>8&1 == 1
+// RevokeGroupCapability revokes a capability granted to a group.
+func (s *Session) RevokeGroupCapability(group string, c Capability) error {
+	if !s.valid() {
+		return errBadSession
 	}
 
-	for _, g := range s.groups {
-		if g == r.Group() {
-			return r.rules>>4&1 == 1
-		}
+	if !s.su {
+		return errNotSU
 	}
 
-	return r.rules&1 == 1
+	return s.p.revokeGroupCapability(group, c)
 }
 
-func (s *Session) Exec(p Privileged, args ...string) (interface{}, error) {
-	if s.CanExec(p) {
-		return p.Exec(args...), nil
+// UserCapabilities lists the capabilities granted to a user directly or
+// through its groups.
+func (s *Session) UserCapabilities(username string) ([]Capability, error) {
+	if !s.valid() {
+		return nil, errBadSession
 	}
-	return nil, errDenied
+
+	return s.p.userCapabilities(username)
+}
+
+func (s *Session) valid() bool {
+
+	return s.live() && !s.restricted
+
+}
+
+// live reports whether the session is logged in and its account hasn't
+// expired, even if it is restricted by an expired password.
+func (s *Session) live() bool {
+
+	_, ok := s.p.sessions[s.SID]
+	return ok && (s.expires == 0 || time.Now().Unix() < s.expires)
+
+}
+
+func (s *Session) CanRead(p Privileged) bool {
+	return s.Explain(p, OpRead).Allowed
+}
+
+func (s *Session) Read(p Privileged, args ...string) (interface{}, error) {
+	return s.dispatch(context.Background(), p, OpRead, &Request{Args: args})
+}
+
+func (s *Session) CanWrite(p Privileged) bool {
+	return s.Explain(p, OpWrite).Allowed
+}
+
+func (s *Session) Write(p Privileged, args ...string) (interface{}, error) {
+	return s.dispatch(context.Background(), p, OpWrite, &Request{Args: args})
+}
+
+func (s Session) CanExec(p Privileged) bool {
+	return s.Explain(p, OpExec).Allowed
+}
+
+func (s *Session) Exec(p Privileged, args ...string) (interface{}, error) {
+	return s.dispatch(context.Background(), p, OpExec, &Request{Args: args})
 }
 
 func (s *Session) CanChgrp(r *Rules, group string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return s.canChgrp(r, group)
+}
+
+func (s *Session) canChgrp(r *Rules, group string) bool {
+	if r.attrs != 0 {
+		return false
+	}
+
 	in := false
 	for _, grp := range s.groups {
 		if grp == group {
@@ -260,15 +471,11 @@ func (s *Session) CanChgrp(r *Rules, group string) bool {
 		}
 	}
 
-	if in && r.Owner() == s.User {
-		return true
-	}
-
-	if in && s.su {
+	if in && r.owner == s.User {
 		return true
 	}
 
-	if s.su {
+	if s.Capable(CapChown) {
 		rows, err := s.p.db.Query("SELECT * FROM groups WHERE name=?", group)
 		if err != nil {
 			return false
@@ -285,8 +492,19 @@ func (s *Session) CanChgrp(r *Rules, group string) bool {
 }
 
 func (s *Session) CanChown(r *Rules, owner string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	if s.su {
+	return s.canChown(r, owner)
+}
+
+func (s *Session) canChown(r *Rules, owner string) bool {
+
+	if r.attrs != 0 {
+		return false
+	}
+
+	if s.Capable(CapChown) {
 		rows, err := s.p.db.Query("SELECT * FROM users WHERE name=?", owner)
 		if err != nil {
 			return false
@@ -302,16 +520,220 @@ func (s *Session) CanChown(r *Rules, owner string) bool {
 
 }
 
+// CanChmod reports whether the session may change the permissions of r to
+// mode, which is either an octal string or a chmod style mode expression.
 func (s *Session) CanChmod(r *Rules, mode string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	if !validRules(mode) {
+	return s.canChmod(r, mode)
+}
+
+func (s *Session) canChmod(r *Rules, mode string) bool {
+
+	if _, err := ParseMode(mode); err != nil || r.attrs != 0 {
 		return false
 	}
 
-	if r.Owner() == s.User || s.su {
+	if r.owner == s.User || s.Capable(CapFowner) {
 		return true
 	}
 
 	return false
 
 }
+
+// Chmod changes the permissions of p to mode, which is either an octal string
+// or a chmod style mode expression. As on Linux the setgid bit of a file is
+// cleared unless the session is a superuser or a member of the file's group.
+func (s *Session) Chmod(p Privileged, mode string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	r := p.Rules()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !s.canChmod(r, mode) {
+		return errDenied
+	}
+
+	m, _ := ParseMode(mode)
+	rules := m.apply(r.rules, isDir(p))
+	if !isDir(p) && !s.Capable(CapFowner) && !s.member(r.group) {
+		rules &^= setgid
+	}
+	r.rules = rules
+
+	return nil
+}
+
+// Chown changes the owner of p.
+func (s *Session) Chown(p Privileged, owner string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	r := p.Rules()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !s.canChown(r, owner) {
+		return errDenied
+	}
+
+	r.owner = owner
+	killSuid(p)
+
+	return nil
+}
+
+// Chgrp changes the group of p.
+func (s *Session) Chgrp(p Privileged, group string) error {
+	if !s.valid() {
+		return errBadSession
+	}
+
+	r := p.Rules()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !s.canChgrp(r, group) {
+		return errDenied
+	}
+
+	r.group = group
+	killSuid(p)
+
+	return nil
+}
+
+// killSuid clears the special bits a change of ownership removes from files,
+// as the kernel does: setuid always, and setgid only when group execute is set
+// since without it the bit marks mandatory locking instead. The caller holds
+// the lock of p's rules.
+func killSuid(p Privileged) {
+	if isDir(p) {
+		return
+	}
+
+	r := p.Rules()
+	r.rules &^= setuid
+	if r.rules&0x0010 != 0 {
+		r.rules &^= setgid
+	}
+}
+
+// CanDelete reports whether the entry p may be removed from the directory dir.
+// It requires write and search permission on dir. If dir has the sticky bit
+// set only the owner of p, the owner of dir or a superuser may remove it.
+// Immutable and append-only entries can't be removed, and neither can entries
+// of immutable or append-only directories.
+func (s *Session) CanDelete(dir, p Privileged) bool {
+	if !s.CanWrite(dir) || !s.CanExec(dir) || p.Rules().Attrs() != 0 {
+		return false
+	}
+
+	d := dir.Rules()
+	if !d.Sticky() {
+		return true
+	}
+
+	return s.Capable(CapFowner) || d.Owner() == s.User || p.Rules().Owner() == s.User
+}
+
+// NewRules returns the rules for a new object created by the session, as
+// open(2) and mkdir(2) would: owned by the session's user, grouped to its
+// primary gid and with the session's umask cleared from mode. As with chmod,
+// the setgid bit is cleared unless the session is a member of that group.
+func (s *Session) NewRules(mode string) (*Rules, error) {
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	r, err := NewRules(s.User, s.gid, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	r.rules &^= s.umaskBits()
+	s.dropSetgid(r)
+	return r, nil
+}
+
+// dropSetgid clears the setgid bit of new rules unless the session is a
+// member of their group or holds CapFowner.
+func (s *Session) dropSetgid(r *Rules) {
+	if !s.Capable(CapFowner) && !s.member(r.group) {
+		r.rules &^= setgid
+	}
+}
+
+// ChildRules returns the rules for a new entry created by the session inside
+// dir. The entry is owned by the session's user and grouped to its gid unless
+// dir has the setgid bit set, in which case the entry inherits dir's group and
+// new directories inherit the setgid bit too.
+//
+// If dir has a default ACL the entry inherits it, limited by mode, and the
+// umask is ignored; new directories also inherit it as their own default ACL.
+// Otherwise the session's umask is applied to mode. New directories are marked
+// as such, see Rules.IsDirectory.
+func (s *Session) ChildRules(dir Privileged, mode string, directory bool) (r *Rules, err error) {
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	d := dir.Rules()
+	d.mu.RLock()
+	def, sgid, group := d.def, d.rules&setgid != 0, d.group
+	d.mu.RUnlock()
+
+	if def == nil {
+		r, err = s.NewRules(mode)
+	} else {
+		r, err = NewRules(s.User, s.gid, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.dir = directory
+
+	if def != nil {
+		r.inherit(def)
+		if directory {
+			r.def = append(ACL(nil), def...)
+		}
+	}
+
+	if sgid {
+		r.group = group
+	}
+
+	if sgid && directory {
+		r.rules |= setgid
+	} else {
+		s.dropSetgid(r)
+	}
+
+	return r, nil
+}
+
+// umaskBits returns the permission bits cleared by the session's umask.
+func (s *Session) umaskBits() uint16 {
+	m, err := NewRules("", "", s.umask)
+	if err != nil {
+		return 0
+	}
+	return m.rules & 0x0777
+}
+
+func (s *Session) member(group string) bool {
+	for _, g := range s.groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
@@ -1 +1,382 @@
 package privileges
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSession000(t *testing.T) {
+	p.newUser("Lana", "Kane")
+	p.newUser("Ray", "Gillette")
+	defer p.deleteGroup("Lana")
+	defer p.deleteUser("Lana")
+	defer p.deleteGroup("Ray")
+	defer p.deleteUser("Ray")
+
+	s, err := p.Login("Lana", "Kane")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	dir, _ := NewRules("Ray", "Ray", "1777")
+	mine, _ := NewRules("Lana", "Lana", "0644")
+	theirs, _ := NewRules("Ray", "Ray", "0644")
+
+	if !s.CanDelete(dir, mine) {
+		t.Error(nil)
+	}
+
+	if s.CanDelete(dir, theirs) {
+		t.Error(nil)
+	}
+
+	dir, _ = NewRules("Ray", "Ray", "0777")
+	if !s.CanDelete(dir, theirs) {
+		t.Error(nil)
+	}
+}
+
+func TestSession001(t *testing.T) {
+	p.newUser("Pam", "Poovey")
+	defer p.deleteGroup("Pam")
+	defer p.deleteUser("Pam")
+
+	s, err := p.Login("Pam", "Poovey")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	dir, _ := NewRules(root, root, "2775")
+
+	r, err := s.ChildRules(dir, "2755", true)
+	if err != nil || r.Owner() != "Pam" || r.Group() != root || r.Octal() != "2755" || !r.IsDirectory() {
+		t.Error(nil)
+	}
+
+	r, err = s.ChildRules(dir, "2755", false)
+	if err != nil || r.Group() != root || r.Octal() != "0755" || r.IsDirectory() {
+		t.Error(nil)
+	}
+
+	dir, _ = NewRules(root, root, "0775")
+	r, err = s.ChildRules(dir, "0644", false)
+	if err != nil || r.Group() != "Pam" {
+		t.Error(nil)
+	}
+}
+
+func TestSession002(t *testing.T) {
+	p.newUser("Cheryl", "Tunt")
+	p.setUmask("0027", "Cheryl")
+	defer p.deleteGroup("Cheryl")
+	defer p.deleteUser("Cheryl")
+
+	s, err := p.Login("Cheryl", "Tunt")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	dir, _ := NewRules(root, root, "2777")
+	r, _ := s.ChildRules(dir, "0666", false)
+	if r.Octal() != "0640" || r.Group() != root || r.DefaultACL() != nil {
+		t.Error(r.Octal())
+	}
+
+	def, _ := ParseACL("user::rwx,user:Ray:rwx,group::r-x,mask::rwx,other::r-x")
+	if dir.SetDefaultACL(def) != nil {
+		t.Error(nil)
+	}
+
+	r, _ = s.ChildRules(dir, "0640", false)
+	want := "user::rw-\nuser:Ray:rwx\ngroup::r-x\nmask::r--\nother::---\n"
+	if r.Octal() != "0640" || r.ACL().String() != want || r.DefaultACL() != nil {
+		t.Error(r.ACL().String())
+	}
+
+	r, _ = s.ChildRules(dir, "0777", true)
+	if r.Octal() != "2775" || r.DefaultACL().String() != def.String() {
+		t.Error(r.Octal())
+	}
+}
+
+func TestSession003(t *testing.T) {
+	p.newUser("Krieger", "Algernop")
+	p.newGroup("lab")
+	p.addToGroup("Krieger", "lab")
+	defer p.deleteGroup("lab")
+	defer p.deleteGroup("Krieger")
+	defer p.deleteUser("Krieger")
+
+	s, err := p.Login("Krieger", "Algernop")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	s.Umask("0077")
+	s.Gid("", "lab")
+
+	r, err := s.NewRules("4777")
+	if err != nil || r.Owner() != "Krieger" || r.Group() != "lab" || r.Octal() != "4700" {
+		t.Error(nil)
+	}
+
+	_, err = s.NewRules("9999")
+	if err != errBadRulesString {
+		t.Error(nil)
+	}
+
+	if _, err = s.Gid("", root); err != errDenied || s.gid != "lab" {
+		t.Error(err)
+	}
+
+	if r, _ = s.NewRules("2750"); r.Octal() != "2700" {
+		t.Error(r.Octal())
+	}
+
+	// A primary group set by an admin doesn't make the user a member.
+	s.gid = root
+	if r, _ = s.NewRules("2750"); r.Group() != root || r.Octal() != "0700" {
+		t.Error(r.Octal())
+	}
+
+	s.Gid("", "Krieger")
+	s.Logout()
+	if _, err = s.NewRules("0700"); err != errBadSession {
+		t.Error(err)
+	}
+}
+
+type dir struct {
+	r *Rules
+}
+
+func (d dir) Rules() *Rules                    { return d.r }
+func (d dir) Read(args ...string) interface{}  { return nil }
+func (d dir) Write(args ...string) interface{} { return nil }
+func (d dir) Exec(args ...string) interface{}  { return nil }
+func (d dir) IsDir() bool                      { return true }
+
+func TestSession004(t *testing.T) {
+	p.newUser("Barry", "Dylan")
+	defer p.deleteGroup("Barry")
+	defer p.deleteUser("Barry")
+
+	s, err := p.Login("Barry", "Dylan")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	su, err := p.Login(root, rootPassword)
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer su.Logout()
+
+	r, _ := NewRules("Barry", root, "2755")
+	if s.Chmod(r, "u+s,go-x") != nil || r.Octal() != "4744" {
+		t.Error(r.Octal())
+	}
+
+	if s.Chmod(r, "bad") != errDenied || s.Chown(r, "guest") != errDenied {
+		t.Error(nil)
+	}
+
+	if su.Chown(r, "guest") != nil || r.Owner() != "guest" || r.Octal() != "0744" {
+		t.Error(r.Octal())
+	}
+
+	if s.Chmod(r, "0777") != errDenied || s.Chgrp(r, "Barry") != errDenied {
+		t.Error(nil)
+	}
+
+	if su.Chmod(r, "6750") != nil || su.Chgrp(r, "Barry") != nil || r.Group() != "Barry" || r.Octal() != "0750" {
+		t.Error(r.Octal())
+	}
+
+	d := dir{r}
+	su.Chmod(d, "g+s,a-x,a+X")
+	if su.Chown(d, "Barry") != nil || r.Octal() != "2751" {
+		t.Error(r.Octal())
+	}
+}
+
+func TestSession005(t *testing.T) {
+	p.newUser("Woodhouse", "Arthur")
+	p.newGroup("butlers")
+	p.addToGroup("Woodhouse", "butlers")
+	defer p.deleteGroup("butlers")
+	defer p.deleteGroup("Woodhouse")
+	defer p.deleteUser("Woodhouse")
+
+	su, err := p.Login(root, rootPassword)
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer su.Logout()
+
+	s, _ := p.Login("Woodhouse", "Arthur")
+	if s.Capable(CapGroupAdmin) || s.NewGroup("valets") != errNotCapable {
+		t.Error(nil)
+	}
+	s.Logout()
+
+	if s.GrantUserCapability("Woodhouse", CapChown) != errBadSession {
+		t.Error(nil)
+	}
+
+	if su.GrantUserCapability("Woodhouse", "CAP_EVERYTHING") != errBadCapability {
+		t.Error(nil)
+	}
+
+	if su.GrantUserCapability("Woodhouse", CapDACReadSearch) != nil ||
+		su.GrantGroupCapability("butlers", CapGroupAdmin) != nil {
+		t.Error(nil)
+	}
+
+	caps, _ := su.UserCapabilities("Woodhouse")
+	if len(caps) != 2 {
+		t.Error(caps)
+	}
+
+	s, _ = p.Login("Woodhouse", "Arthur")
+	defer s.Logout()
+
+	if s.GrantUserCapability("Woodhouse", CapChown) != errNotSU {
+		t.Error(nil)
+	}
+
+	if !s.Capable(CapGroupAdmin) || s.Capable(CapUserAdmin) {
+		t.Error(nil)
+	}
+
+	if s.NewGroup("valets") != nil || s.DeleteGroup("valets") != nil {
+		t.Error(nil)
+	}
+
+	if s.NewUser("valet", "", "") != errNotCapable {
+		t.Error(nil)
+	}
+
+	r, _ := NewRules(root, root, "0000")
+	if !s.CanRead(r) || s.CanWrite(r) || s.CanExec(r) || !s.CanExec(dir{r}) {
+		t.Error(nil)
+	}
+
+	if s.CanChown(r, "Woodhouse") || s.CanChmod(r, "0777") {
+		t.Error(nil)
+	}
+
+	su.RevokeUserCapability("Woodhouse", CapDACReadSearch)
+	su.RevokeGroupCapability("butlers", CapGroupAdmin)
+	caps, _ = su.UserCapabilities("Woodhouse")
+	if len(caps) != 0 {
+		t.Error(caps)
+	}
+}
+
+func TestSession006(t *testing.T) {
+	p.newUser("Zed", "Zed")
+	p.newGroup("vault")
+	p.grantGroupCapability("vault", CapMACAdmin)
+	p.grantUserCapability("Zed", CapUserAdmin)
+	p.grantUserCapability("Zed", CapGroupAdmin)
+	defer p.deleteGroup("vault")
+	defer p.deleteGroup("Zed")
+	defer p.deleteUser("Zed")
+
+	p.newUser("Ziggy", "Stardust")
+	p.grantUserCapability("Ziggy", CapChown)
+	defer p.deleteGroup("Ziggy")
+	defer p.deleteUser("Ziggy")
+
+	s, err := p.Login("Zed", "Zed")
+	if err != nil {
+		t.Fatal(nil)
+	}
+	defer s.Logout()
+
+	salt, hash := saltAndHash("owned")
+	if s.ChangePassword(root, salt, hash) != errNotSU {
+		t.Error(nil)
+	}
+
+	if _, err := p.Login(root, "owned"); err != errBadCredentials {
+		t.Error(err)
+	}
+
+	if s.ChangePassword("Ziggy", salt, hash) != errNotCapable {
+		t.Error(nil)
+	}
+
+	salt, hash = saltAndHash("")
+	if s.ChangePassword("guest", salt, hash) != nil {
+		t.Error(nil)
+	}
+
+	if s.UserAddGroup("Zed", root) != errNotSU || s.UserAddGroup("Zed", "vault") != errNotCapable {
+		t.Error(nil)
+	}
+
+	if in, _ := p.inGroup("Zed", root); in {
+		t.Error(nil)
+	}
+
+	if s.UserRemoveGroup(root, root) != errNotSU || s.DeleteUser(root) != errNotSU {
+		t.Error(nil)
+	}
+
+	if in, _ := p.inGroup(root, root); !in {
+		t.Error(nil)
+	}
+
+	if s.DeleteUser("Ziggy") != errNotCapable {
+		t.Error(nil)
+	}
+
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+
+	if su.UserAddGroup("Zed", "vault") != nil {
+		t.Error(nil)
+	}
+}
+
+func TestSession007(t *testing.T) {
+	su, _ := p.Login(root, rootPassword)
+	defer su.Logout()
+	guest, _ := p.Login("guest", "")
+	defer guest.Logout()
+
+	r, _ := NewRules(root, root, "0644")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			su.Chmod(r, "o-r")
+			su.Chmod(r, "o+r")
+			su.Chgrp(r, "guest")
+			su.Chgrp(r, root)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			guest.CanRead(r)
+			guest.Effective(r)
+			r.Symbolic(false)
+			r.ACLText()
+		}
+	}()
+	wg.Wait()
+
+	if r.Octal() != "0644" || r.Group() != root {
+		t.Error(r.Octal(), r.Group())
+	}
+}
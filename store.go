@@ -0,0 +1,205 @@
+package privileges
+
+import (
+	"database/sql"
+	"path"
+	"strings"
+)
+
+// The object store keeps Rules for a hierarchy of slash separated absolute
+// paths in the privileges database. Every path except / must have its parent
+// stored, owners and groups must exist, and a path can only be deleted once
+// it has no children. Whether a path is a directory is kept with its rules,
+// see Rules.IsDirectory.
+
+func (p *Privileges) createObjectsTable() {
+
+	p.db.Exec("CREATE TABLE IF NOT EXISTS objects (" +
+		"path VARCHAR(4096) PRIMARY KEY, " +
+		"parent VARCHAR(4096) NULL, " +
+		"owner VARCHAR(64) NOT NULL, " +
+		"grp VARCHAR(64) NOT NULL, " +
+		"rules VARCHAR(4) NOT NULL, " +
+		"acl TEXT NULL, " +
+		"defacl TEXT NULL, " +
+		"FOREIGN KEY (parent) REFERENCES objects(path), " +
+		"FOREIGN KEY (owner) REFERENCES users(name), " +
+		"FOREIGN KEY (grp) REFERENCES groups(name)" +
+		");")
+	p.db.Exec("CREATE INDEX IF NOT EXISTS objectsparent ON objects(parent);")
+
+	// Columns added after the table was introduced. Adding them fails
+	// harmlessly once they exist.
+	p.db.Exec("ALTER TABLE objects ADD COLUMN attrs INTEGER NOT NULL DEFAULT 0;")
+	p.db.Exec("ALTER TABLE objects ADD COLUMN verbs TEXT NOT NULL DEFAULT '';")
+	p.db.Exec("ALTER TABLE objects ADD COLUMN label TEXT NOT NULL DEFAULT '0';")
+	p.db.Exec("ALTER TABLE objects ADD COLUMN dir INTEGER NOT NULL DEFAULT 0;")
+
+}
+
+// cleanPath returns the canonical form of an absolute path and its parent,
+// which is empty for /.
+func cleanPath(name string) (string, string, error) {
+
+	if !strings.HasPrefix(name, "/") {
+		return "", "", errBadPath
+	}
+
+	name = path.Clean(name)
+	if name == "/" {
+		return name, "", nil
+	}
+	return name, path.Dir(name), nil
+
+}
+
+// GetRules returns the rules stored for a path.
+func (p *Privileges) GetRules(name string) (*Rules, error) {
+
+	name, _, err := cleanPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var owner, group, rules string
+	var text, def sql.NullString
+	var attrs Attr
+	var verbs, label string
+	var dir bool
+	row := p.db.QueryRow("SELECT owner, grp, rules, acl, defacl, attrs, verbs, label, dir FROM objects WHERE path=?", name)
+	err = row.Scan(&owner, &group, &rules, &text, &def, &attrs, &verbs, &label, &dir)
+	if err == sql.ErrNoRows {
+		return nil, errNoObject
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewRules(owner, group, rules)
+	if err != nil {
+		return nil, err
+	}
+	r.attrs, r.dir = attrs, dir
+
+	if err = r.setVerbs(verbs); err != nil {
+		return nil, err
+	}
+
+	if r.label, err = ParseLabel(label); err != nil {
+		return nil, err
+	}
+
+	if text.Valid {
+		a, err := ParseACL(text.String)
+		if err != nil {
+			return nil, err
+		}
+		r.SetACL(a)
+	}
+
+	if def.Valid {
+		a, err := ParseACL(def.String)
+		if err != nil {
+			return nil, err
+		}
+		r.SetDefaultACL(a)
+	}
+
+	return r, nil
+
+}
+
+// PutRules stores the rules for a path, replacing any already stored.
+func (p *Privileges) PutRules(name string, r *Rules) error {
+
+	name, parent, err := cleanPath(name)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	owner, group, rules, attrs, verbs, label, dir := r.owner, r.group, r.octal(), r.attrs, r.verbList(), r.label.String(), r.dir
+	var text, def sql.NullString
+	if r.acl != nil {
+		text = sql.NullString{String: r.accessACL().String(), Valid: true}
+	}
+	if r.def != nil {
+		def = sql.NullString{String: r.def.String(), Valid: true}
+	}
+	r.mu.RUnlock()
+
+	var up sql.NullString
+	if parent != "" {
+		up = sql.NullString{String: parent, Valid: true}
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE objects SET owner=?, grp=?, rules=?, acl=?, defacl=?, attrs=?, verbs=?, label=?, dir=? WHERE path=?",
+		owner, group, rules, text, def, attrs, verbs, label, dir, name)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		_, err = tx.Exec("INSERT INTO objects(path, parent, owner, grp, rules, acl, defacl, attrs, verbs, label, dir) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			name, up, owner, group, rules, text, def, attrs, verbs, label, dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+
+}
+
+// DeleteRules removes the rules stored for a path. It fails if the path still
+// has children.
+func (p *Privileges) DeleteRules(name string) error {
+
+	name, _, err := cleanPath(name)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.db.Exec("DELETE FROM objects WHERE path=?", name)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errNoObject
+	}
+	return nil
+
+}
+
+// ListRules returns the paths of the children stored under a path.
+func (p *Privileges) ListRules(name string) ([]string, error) {
+
+	name, _, err := cleanPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []string
+
+	rows, err := p.db.Query("SELECT path FROM objects WHERE parent=? ORDER BY path", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var child string
+	for rows.Next() {
+		rows.Scan(&child)
+		children = append(children, child)
+	}
+
+	return children, nil
+
+}
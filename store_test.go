@@ -0,0 +1,77 @@
+package privileges
+
+import (
+	"testing"
+)
+
+func TestStore00(t *testing.T) {
+	p.newUser("Pam", "Poovey")
+	defer p.deleteGroup("Pam")
+	defer p.deleteUser("Pam")
+
+	r, _ := NewRules(root, root, "1777")
+	def, _ := ParseACL("user::rwx,user:Pam:rwx,group::r-x,mask::rwx,other::---")
+	r.SetDefaultACL(def)
+	r.SetDirectory(true)
+
+	if p.PutRules("tmp", r) != errBadPath {
+		t.Error(nil)
+	}
+
+	if p.PutRules("/tmp", r) == nil {
+		t.Error(nil)
+	}
+
+	top, _ := NewRules(root, root, "0755")
+	if p.PutRules("/", top) != nil || p.PutRules("/tmp/", r) != nil {
+		t.Error(nil)
+	}
+	defer p.DeleteRules("/")
+	defer p.DeleteRules("/tmp")
+
+	f, _ := NewRules("Pam", "Pam", "0640")
+	a, _ := ParseACL("user::rw-,group::r--,group:guest:rw-,mask::rw-,other::---")
+	f.SetACL(a)
+	f.attrs = AttrAppend
+	f.SetVerb("share", "ug")
+	f.label = Label{Level: 1, Compartments: []string{"hr"}}
+	if p.PutRules("/tmp/notes", f) != nil {
+		t.Error(nil)
+	}
+
+	q, err := p.GetRules("/tmp/../tmp/notes")
+	if err != nil || q.Symbolic(false) != f.Symbolic(false) || q.ACL().String() != f.ACL().String() || q.Attrs() != AttrAppend || q.Verbs() != "share=ug" || q.Label().String() != "1:hr" || q.IsDirectory() {
+		t.Error(err)
+	}
+
+	q, err = p.GetRules("/tmp")
+	if err != nil || q.Octal() != "1777" || q.DefaultACL().String() != def.String() || !q.IsDirectory() {
+		t.Error(err)
+	}
+
+	children, err := p.ListRules("/")
+	if err != nil || len(children) != 1 || children[0] != "/tmp" {
+		t.Error(children)
+	}
+
+	f, _ = NewRules("nobody", "Pam", "0640")
+	if p.PutRules("/tmp/notes", f) == nil {
+		t.Error(nil)
+	}
+
+	if p.deleteUser("Pam") == nil {
+		t.Error(nil)
+	}
+
+	if p.DeleteRules("/tmp") == nil {
+		t.Error(nil)
+	}
+
+	if p.DeleteRules("/tmp/notes") != nil || p.DeleteRules("/tmp/notes") != errNoObject {
+		t.Error(nil)
+	}
+
+	if _, err = p.GetRules("/tmp/notes"); err != errNoObject {
+		t.Error(nil)
+	}
+}
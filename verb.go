@@ -0,0 +1,266 @@
+package privileges
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Built in verbs, checked against the rwx bits.
+const (
+	VerbRead  = "read"
+	VerbWrite = "write"
+	VerbExec  = "exec"
+)
+
+// Doer is implemented by Privileged objects that support custom verbs.
+type Doer interface {
+	Privileged
+	Do(verb string, args ...string) (interface{}, error)
+}
+
+// verbDef is a registered custom verb: the classes (a mask over the rwx bits)
+// granted it by default, and the direction of the information flow it
+// performs for mandatory access control.
+type verbDef struct {
+	classes uint16
+	op      Op
+}
+
+// verbs holds the registered custom verbs of each object type.
+var verbs = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]verbDef
+}{m: make(map[reflect.Type]map[string]verbDef)}
+
+// RegisterVerb registers a custom verb such as "approve" or "share" for
+// objects of the same type as obj. op tells mandatory access control whether
+// the verb reads from the object, writes to it, or both (OpRead, OpWrite or
+// their union). who lists the classes granted the verb by default, using
+// chmod's letters (u, g, o, a); an empty who grants it to nobody. Individual
+// objects can override who with Rules.SetVerb.
+func RegisterVerb(obj Privileged, verb string, op Op, who string) error {
+	classes, err := parseVerb(verb, who)
+	if err != nil {
+		return err
+	}
+
+	if op == 0 || op&^(OpRead|OpWrite) != 0 {
+		return errBadVerb
+	}
+
+	t := reflect.TypeOf(obj)
+
+	verbs.Lock()
+	defer verbs.Unlock()
+
+	if verbs.m[t] == nil {
+		verbs.m[t] = make(map[string]verbDef)
+	}
+	verbs.m[t][verb] = verbDef{classes, op}
+
+	return nil
+}
+
+func parseVerb(verb, who string) (uint16, error) {
+	switch verb {
+	case "", VerbRead, VerbWrite, VerbExec:
+		return 0, errBadVerb
+	}
+
+	if strings.ContainsAny(verb, ",= \n") {
+		return 0, errBadVerb
+	}
+
+	var classes uint16
+	for i := 0; i < len(who); i++ {
+		switch who[i] {
+		case 'u':
+			classes |= classUser
+		case 'g':
+			classes |= classGroup
+		case 'o':
+			classes |= classOther
+		case 'a':
+			classes |= classAll
+		default:
+			return 0, errBadVerb
+		}
+	}
+
+	return classes, nil
+}
+
+// SetVerb grants a custom verb to the classes in who on these rules only,
+// overriding the default registered for the object type.
+func (r *Rules) SetVerb(verb, who string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.setVerb(verb, who)
+}
+
+func (r *Rules) setVerb(verb, who string) error {
+	classes, err := parseVerb(verb, who)
+	if err != nil {
+		return err
+	}
+
+	if r.verbs == nil {
+		r.verbs = make(map[string]uint16)
+	}
+	r.verbs[verb] = classes
+
+	return nil
+}
+
+// Verbs returns the custom verbs set on the rules and the classes granted
+// each, in the form approve=ug,share=u.
+func (r *Rules) Verbs() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.verbList()
+}
+
+func (r *Rules) verbList() string {
+	var list []string
+	for verb, classes := range r.verbs {
+		who := ""
+		for i, c := range "ugo" {
+			if classes&(classUser>>(4*uint(i))) != 0 {
+				who += string(c)
+			}
+		}
+		list = append(list, verb+"="+who)
+	}
+
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+// setVerbs parses the form returned by Verbs.
+func (r *Rules) setVerbs(list string) error {
+	r.verbs = nil
+	if list == "" {
+		return nil
+	}
+
+	for _, v := range strings.Split(list, ",") {
+		i := strings.IndexByte(v, '=')
+		if i < 0 {
+			return errBadVerb
+		}
+		if err := r.setVerb(v[:i], v[i+1:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verbClasses returns the classes granted a custom verb on p. The caller holds
+// the lock of p's rules.
+func verbClasses(p Privileged, verb string) (uint16, bool) {
+	if classes, ok := p.Rules().verbs[verb]; ok {
+		return classes, true
+	}
+
+	verbs.RLock()
+	defer verbs.RUnlock()
+
+	def, ok := verbs.m[reflect.TypeOf(p)][verb]
+	return def.classes, ok
+}
+
+// verbOp returns the direction registered for a custom verb on p. Verbs only
+// set on the rules are treated as both reading and writing.
+func verbOp(p Privileged, verb string) Op {
+	verbs.RLock()
+	defer verbs.RUnlock()
+
+	if def, ok := verbs.m[reflect.TypeOf(p)][verb]; ok {
+		return def.op
+	}
+	return OpRead | OpWrite
+}
+
+// Can reports whether the session may perform verb on p. The built in verbs
+// read, write and exec are the same as CanRead, CanWrite and CanExec. Custom
+// verbs are granted to the owner, owning group and other classes; ACL entries
+// don't apply to them. CapDACOverride grants every custom verb. Mandatory
+// access control applies to custom verbs in their registered direction.
+func (s *Session) Can(p Privileged, verb string) bool {
+	switch verb {
+	case VerbRead:
+		return s.CanRead(p)
+	case VerbWrite:
+		return s.CanWrite(p)
+	case VerbExec:
+		return s.CanExec(p)
+	}
+
+	return s.checkVerb(p, verb) == nil
+}
+
+// checkVerb returns nil if the session may perform the custom verb on p, or
+// the error telling why not.
+func (s *Session) checkVerb(p Privileged, verb string) error {
+	r := p.Rules()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	classes, ok := verbClasses(p, verb)
+	if !ok {
+		return errBadVerb
+	}
+
+	var allowed bool
+	switch {
+	case s.p.dacOverride && s.Capable(CapDACOverride):
+		allowed = true
+	case s.User == r.owner:
+		allowed = classes&classUser != 0
+	case s.member(r.group):
+		allowed = classes&classGroup != 0
+	default:
+		allowed = classes&classOther != 0
+	}
+
+	if !allowed {
+		return errDenied
+	}
+
+	if !s.mac(r, verbOp(p, verb)) {
+		return errDeniedMAC
+	}
+	return nil
+}
+
+// Do performs verb on p if the session may. Built in verbs call Read, Write
+// and Exec; custom verbs need p to implement Doer.
+func (s *Session) Do(p Privileged, verb string, args ...string) (interface{}, error) {
+	switch verb {
+	case VerbRead:
+		return s.Read(p, args...)
+	case VerbWrite:
+		return s.Write(p, args...)
+	case VerbExec:
+		return s.Exec(p, args...)
+	}
+
+	if !s.valid() {
+		return nil, errBadSession
+	}
+
+	if err := s.checkVerb(p, verb); err != nil {
+		return nil, err
+	}
+
+	d, ok := p.(Doer)
+	if !ok {
+		return nil, errBadVerb
+	}
+	return d.Do(verb, args...)
+}
@@ -0,0 +1,117 @@
+package privileges
+
+import (
+	"testing"
+)
+
+type invoice struct {
+	r        *Rules
+	approved bool
+}
+
+func (i *invoice) Rules() *Rules                    { return i.r }
+func (i *invoice) Read(args ...string) interface{}  { return i.approved }
+func (i *invoice) Write(args ...string) interface{} { return nil }
+func (i *invoice) Exec(args ...string) interface{}  { return nil }
+
+func (i *invoice) Do(verb string, args ...string) (interface{}, error) {
+	i.approved = verb == "approve"
+	return i.approved, nil
+}
+
+func TestVerb00(t *testing.T) {
+	p.newUser("Malory", "Archer")
+	p.newUser("Cyril", "Figgis")
+	p.addToGroup("Cyril", "Malory")
+	defer p.deleteGroup("Cyril")
+	defer p.deleteUser("Cyril")
+	defer p.deleteGroup("Malory")
+	defer p.deleteUser("Malory")
+
+	malory, _ := p.Login("Malory", "Archer")
+	defer malory.Logout()
+	cyril, _ := p.Login("Cyril", "Figgis")
+	defer cyril.Logout()
+	guest, _ := p.Login("guest", "")
+	defer guest.Logout()
+
+	if RegisterVerb(&invoice{}, "read", OpRead, "a") != errBadVerb || RegisterVerb(&invoice{}, "approve", OpWrite, "ux") != errBadVerb ||
+		RegisterVerb(&invoice{}, "approve", OpExec, "u") != errBadVerb {
+		t.Error(nil)
+	}
+
+	if RegisterVerb(&invoice{}, "approve", OpWrite, "u") != nil || RegisterVerb(&invoice{}, "reject", OpWrite, "ug") != nil {
+		t.Error(nil)
+	}
+
+	r, _ := NewRules("Malory", "Malory", "0644")
+	inv := &invoice{r: r}
+
+	if !cyril.Can(inv, "read") || cyril.Can(inv, "write") || cyril.Can(inv, "approve") || !cyril.Can(inv, "reject") {
+		t.Error(nil)
+	}
+
+	if guest.Can(inv, "reject") || guest.Can(inv, "audit") || guest.Can(r, "approve") {
+		t.Error(nil)
+	}
+
+	if _, err := cyril.Do(inv, "approve"); err != errDenied {
+		t.Error(nil)
+	}
+
+	if _, err := cyril.Do(inv, "audit"); err != errBadVerb {
+		t.Error(nil)
+	}
+
+	if v, err := malory.Do(inv, "approve"); err != nil || v != true {
+		t.Error(err)
+	}
+
+	if v, err := guest.Do(inv, "read"); err != nil || v != true {
+		t.Error(err)
+	}
+
+	r.SetVerb("approve", "ug")
+	if !cyril.Can(inv, "approve") || r.Verbs() != "approve=ug" {
+		t.Error(nil)
+	}
+}
+
+func TestVerb01(t *testing.T) {
+	p.newUser("Sterling", "Archer")
+	defer p.deleteGroup("Sterling")
+	defer p.deleteUser("Sterling")
+
+	s, _ := p.Login("Sterling", "Archer")
+	defer s.Logout()
+
+	RegisterVerb(&invoice{}, "audit", OpRead, "a")
+	RegisterVerb(&invoice{}, "approve", OpWrite, "u")
+
+	r, _ := NewRules("Sterling", "Sterling", "0644")
+	r.label = Label{Level: 5}
+	inv := &invoice{r: r}
+
+	p.SetMAC(true)
+	defer p.SetMAC(false)
+
+	if s.CanRead(inv) || s.Can(inv, "audit") || !s.Can(inv, "approve") {
+		t.Error(nil)
+	}
+
+	if _, err := s.Do(inv, "audit"); err != errDeniedMAC {
+		t.Error(err)
+	}
+
+	r.label = Label{}
+	s.clearance = Label{Level: 5}
+	if !s.Can(inv, "audit") || s.Can(inv, "approve") {
+		t.Error(nil)
+	}
+
+	// Verbs only set on the rules need both directions to pass.
+	r.SetVerb("share", "u")
+	if _, err := s.Do(inv, "share"); err != errDeniedMAC {
+		t.Error(err)
+	}
+}